@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+	"github.com/biya-coin/injective-chronos-go/internal/task"
+)
+
+// runJobs implements `main [-f config] jobs [list|trigger|pause|resume]`, the
+// CLI equivalent of the /api/admin/jobs endpoints. It talks to Mongo and Redis
+// directly, so no admin token is needed.
+func runJobs(ctx *svc.ServiceContext, args []string) int {
+	cmd := "list"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	switch cmd {
+	case "list":
+		for _, name := range task.JobNames(ctx) {
+			paused, err := ctx.Store.JobStates.Paused(sigCtx, name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "jobs: %v\n", err)
+				return 1
+			}
+			last := "never run"
+			if runs, err := ctx.Store.JobRuns.Runs(sigCtx, name, 1); err == nil && len(runs) > 0 {
+				r := runs[0]
+				last = fmt.Sprintf("last %s fetched %d written %d failed %d skipped %v",
+					r.Start.UTC().Format("2006-01-02 15:04:05"), r.Fetched, r.Written, r.Failed, r.Skipped)
+			}
+			state := ""
+			if paused {
+				state = "PAUSED"
+			}
+			fmt.Printf("%-34s %-6s %s\n", name, state, last)
+		}
+		return 0
+	case "trigger":
+		fs := flag.NewFlagSet("jobs trigger", flag.ContinueOnError)
+		market := fs.String("market", "", "only this market id (derivative: symbol)")
+		resolution := fs.String("resolution", "", "only this resolution")
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: jobs trigger <name> [-market id] [-resolution res]")
+			return 2
+		}
+		name := args[0]
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		run, err := task.ManualRun(ctx, name, task.Target{Market: *market, Resolution: *resolution})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "jobs: %v\n", err)
+			return 2
+		}
+		res := run(sigCtx)
+		out, _ := json.MarshalIndent(res, "", "  ")
+		fmt.Println(string(out))
+		if res.Skipped || res.Failed > 0 {
+			return 1
+		}
+		return 0
+	case "pause", "resume":
+		if len(args) != 1 {
+			fmt.Fprintf(os.Stderr, "usage: jobs %s <name>\n", cmd)
+			return 2
+		}
+		if err := task.SetPaused(sigCtx, ctx, args[0], cmd == "pause"); err != nil {
+			fmt.Fprintf(os.Stderr, "jobs: %v\n", err)
+			return 1
+		}
+		fmt.Printf("%s %sd\n", args[0], cmd)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown jobs command %q (want list, trigger, pause or resume)\n", cmd)
+		return 2
+	}
+}
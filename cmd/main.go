@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/zeromicro/go-zero/core/conf"
 	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/proc"
 	"github.com/zeromicro/go-zero/rest"
 
 	"github.com/biya-coin/injective-chronos-go/internal/config"
@@ -20,9 +23,31 @@ import (
 // Config file selection: flag -f overrides; otherwise use ENV (default "dev").
 var configFile = flag.String("f", "", "the config file")
 
+// Run modes: api serves the public routes only, worker runs the cron jobs and
+// serves only /healthz, all does both.
+const (
+	modeAPI    = "api"
+	modeWorker = "worker"
+	modeAll    = "all"
+)
+
+var mode = flag.String("mode", modeAll, "run mode: api, worker or all")
+
+// subcommands run once against the stores and exit; -mode does not apply.
+var subcommands = map[string]func(ctx *svc.ServiceContext, args []string) int{
+	"migrate":  runMigrate,
+	"backfill": runBackfill,
+	"jobs":     runJobs,
+}
+
 func main() {
 	flag.Parse()
 	// initialize logging via side-effect import
+	run, isSubcommand := subcommands[flag.Arg(0)]
+	if !isSubcommand && *mode != modeAPI && *mode != modeWorker && *mode != modeAll {
+		fmt.Fprintf(os.Stderr, "unknown -mode %q (want api, worker or all)\n", *mode)
+		os.Exit(2)
+	}
 
 	var c config.Config
 	// resolve config path based on -f flag or ENV
@@ -40,24 +65,57 @@ func main() {
 	_ = logx.SetUp(logx.LogConf{Encoding: "plain"})
 
 	logx.Infof("env=%s config=%s", env, cfgPath)
-	logx.Infof("starting %s on %s:%d", c.Name, c.Host, c.Port)
 
 	ctx := svc.NewServiceContext(c)
+	if isSubcommand {
+		os.Exit(run(ctx, flag.Args()[1:]))
+	}
+	logx.Infof("starting %s in %s mode on %s:%d", c.Name, *mode, c.Host, c.Port)
+	if c.Mongo.AutoMigrate {
+		autoMigrate(ctx)
+	}
+
+	// go-zero 收到 SIGTERM/SIGINT 后先通知 wrap-up 监听者，随后关闭 HTTP 服务，
+	// 超过 force-quit 时间仍未退出则强制结束进程：留出等待任务的时间。
+	shutdownTimeout := time.Duration(c.Cron.ShutdownTimeoutSec) * time.Second
+	proc.SetTimeToForceQuit(shutdownTimeout + 10*time.Second)
+
 	server := rest.MustNewServer(c.RestConf)
 	defer server.Stop()
 
-	handler.RegisterHandlers(server, ctx)
+	// worker 只暴露健康检查（指标由 go-zero DevServer 在独立端口提供），不注册对外路由
+	if *mode != modeWorker {
+		handler.RegisterHandlers(server, ctx)
+	}
 	server.AddRoutes([]rest.Route{{
 		Method: "GET",
 		Path:   "/healthz",
 		Handler: func(w http.ResponseWriter, r *http.Request) {
-			handler.HealthHandler(w, r)
+			handler.HealthHandler(ctx, w, r)
 		},
 	}})
 
-	// start cron
-	task.StartCron(ctx)
+	// start cron; stop scheduling as soon as the shutdown signal arrives
+	if *mode != modeAPI {
+		if !c.Cron.Enabled {
+			logx.Errorf("running in %s mode with Cron.Enabled=false: no jobs will run", *mode)
+		}
+		cronCtx, stopCron := context.WithCancel(ctx.Context())
+		proc.AddWrapUpListener(stopCron)
+		task.StartCron(cronCtx, ctx)
+	}
 
 	fmt.Printf("listening on %s:%d\n", c.Host, c.Port)
 	server.Start()
+
+	// Start returns once the HTTP server has shut down
+	logx.Infof("shutting down, waiting up to %s for running jobs", shutdownTimeout)
+	if !ctx.Shutdown(shutdownTimeout) {
+		logx.Errorf("jobs still running after %s, releasing their task locks", shutdownTimeout)
+		task.ReleaseTaskLocks(ctx)
+	}
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx.Close(closeCtx)
+	logx.Info("shutdown complete")
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/zeromicro/go-zero/core/logx"
+
+	"github.com/biya-coin/injective-chronos-go/internal/migrate"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+func newMigrateRunner(ctx *svc.ServiceContext) *migrate.Runner {
+	return migrate.NewRunner(migrate.Collections{
+		Spot:       ctx.SpotColl,
+		Derivative: ctx.DerivativeColl,
+		Market:     ctx.MarketColl,
+	}, migrate.All())
+}
+
+// autoMigrate applies pending migrations on startup. Failures are logged but do
+// not stop the service, which keeps working against the previous schema.
+func autoMigrate(ctx *svc.ServiceContext) {
+	applied, err := newMigrateRunner(ctx).Run(context.Background())
+	for _, m := range applied {
+		logx.Infof("migrate: applied %d %s", m.Version, m.Name)
+	}
+	if err != nil {
+		logx.Errorf("migrate: %v", err)
+	}
+}
+
+// runMigrate implements `main [-f config] migrate [up|status]`.
+func runMigrate(ctx *svc.ServiceContext, args []string) int {
+	runner := newMigrateRunner(ctx)
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch cmd {
+	case "up":
+		applied, err := runner.Run(context.Background())
+		for _, m := range applied {
+			fmt.Printf("applied %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("nothing to migrate")
+		}
+		return 0
+	case "status":
+		status, err := runner.Status(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+			return 1
+		}
+		for _, m := range runner.Migrations() {
+			state := "pending"
+			if rec, ok := status[m.Version]; ok {
+				state = rec.State
+			}
+			fmt.Printf("%3d %-40s %s\n", m.Version, m.Name, state)
+		}
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q (want up or status)\n", cmd)
+		return 2
+	}
+}
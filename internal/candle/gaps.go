@@ -0,0 +1,38 @@
+package candle
+
+// Gap is a run of missing bars; From and To are the start times of the first
+// and last missing bar.
+type Gap struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// Bars returns the number of missing bars in g.
+func (g Gap) Bars(width int64) int64 {
+	return (g.To-g.From)/width + 1
+}
+
+// Gaps returns the holes between consecutive stored bar times (ascending) of a
+// fixed-width series. Only holes between two stored bars are reported; the
+// region after the newest bar belongs to regular ingestion.
+func Gaps(times []int64, width int64) []Gap {
+	if width <= 0 {
+		return nil
+	}
+	var out []Gap
+	for i := 1; i < len(times); i++ {
+		if times[i]-times[i-1] > width {
+			out = append(out, Gap{From: times[i-1] + width, To: times[i] - width})
+		}
+	}
+	return out
+}
+
+// Expected returns how many bars a gap-free series with the given first and
+// last bar times holds.
+func Expected(first, last, width int64) int64 {
+	if width <= 0 || last < first {
+		return 0
+	}
+	return (last-first)/width + 1
+}
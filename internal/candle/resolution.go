@@ -0,0 +1,104 @@
+package candle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unit is the calendar unit of a Resolution.
+type Unit int
+
+const (
+	Minute Unit = iota
+	Day
+	Week
+	Month
+)
+
+// Resolution is a bar width in TradingView terms: N minutes, days, weeks or
+// months.
+type Resolution struct {
+	N    int
+	Unit Unit
+}
+
+// ParseResolution parses TradingView resolutions ("1", "45", "D", "2D", "1W",
+// "1M") and the aliases stored by this service ("24h", "1d", "1w"). A bare
+// number is minutes; "M" is always months, there is no lowercase "m".
+func ParseResolution(s string) (Resolution, error) {
+	switch s {
+	case "24h", "1d":
+		return Resolution{N: 1, Unit: Day}, nil
+	case "1w":
+		return Resolution{N: 1, Unit: Week}, nil
+	}
+	if s == "" {
+		return Resolution{}, fmt.Errorf("candle: empty resolution")
+	}
+	unit := Minute
+	digits := s
+	switch s[len(s)-1] {
+	case 'D':
+		unit, digits = Day, s[:len(s)-1]
+	case 'W':
+		unit, digits = Week, s[:len(s)-1]
+	case 'M':
+		unit, digits = Month, s[:len(s)-1]
+	}
+	n := 1
+	if digits != "" {
+		v, err := strconv.Atoi(digits)
+		if err != nil || v <= 0 || strings.HasPrefix(digits, "+") {
+			return Resolution{}, fmt.Errorf("candle: invalid resolution %q", s)
+		}
+		n = v
+	} else if unit == Minute {
+		return Resolution{}, fmt.Errorf("candle: invalid resolution %q", s)
+	}
+	return Resolution{N: n, Unit: unit}, nil
+}
+
+// Seconds returns the fixed bar width, or 0 for months, whose length varies.
+func (r Resolution) Seconds() int64 {
+	switch r.Unit {
+	case Minute:
+		return int64(r.N) * 60
+	case Day:
+		return int64(r.N) * 86400
+	case Week:
+		return int64(r.N) * 7 * 86400
+	}
+	return 0
+}
+
+// epochMonday is the first Monday after the unix epoch (1970-01-05 UTC); weekly
+// buckets are counted from it so weeks start on Monday 00:00 UTC.
+const epochMonday = 4 * 86400
+
+// Bucket returns the start of the bar containing t. Minute and day bars are
+// aligned to the unix epoch, weeks to Monday and months to the first of the
+// month, all in UTC.
+func (r Resolution) Bucket(t int64) int64 {
+	switch r.Unit {
+	case Week:
+		return Align(t-epochMonday, r.Seconds()) + epochMonday
+	case Month:
+		tm := time.Unix(t, 0).UTC()
+		months := tm.Year()*12 + int(tm.Month()) - 1
+		months -= months % r.N
+		return time.Date(months/12, time.Month(months%12+1), 1, 0, 0, 0, 0, time.UTC).Unix()
+	}
+	return Align(t, r.Seconds())
+}
+
+// Seconds returns the bar width of a resolution string in seconds, or 0 when
+// it is unknown or has no fixed width (months).
+func Seconds(resolution string) int64 {
+	r, err := ParseResolution(resolution)
+	if err != nil {
+		return 0
+	}
+	return r.Seconds()
+}
@@ -0,0 +1,108 @@
+package candle
+
+import (
+	"math"
+	"sort"
+
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+// Align returns the start of the width-second bucket containing t. Buckets are
+// aligned to the unix epoch, so daily bars start at 00:00 UTC.
+func Align(t, width int64) int64 {
+	r := t % width
+	if r < 0 {
+		r += width
+	}
+	return t - r
+}
+
+// Rollup aggregates the bars of one series into bars of the target resolution;
+// see Resample.
+func Rollup(bars []store.Candle, resolution string) ([]store.Candle, error) {
+	r, err := ParseResolution(resolution)
+	if err != nil {
+		return nil, err
+	}
+	return Resample(bars, r, resolution), nil
+}
+
+// Resample aggregates the bars of one series into r-sized bars labelled with
+// resolution: open of the first bar, high/low extremes, close of the last bar
+// and summed volume. Input order does not matter; the result is ordered by t.
+func Resample(bars []store.Candle, r Resolution, resolution string) []store.Candle {
+	sorted := append([]store.Candle(nil), bars...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].T < sorted[j].T })
+
+	var out []store.Candle
+	for _, b := range sorted {
+		start := r.Bucket(b.T)
+		if n := len(out); n > 0 && out[n-1].T == start {
+			cur := &out[n-1]
+			cur.H = math.Max(cur.H, b.H)
+			cur.L = math.Min(cur.L, b.L)
+			cur.C = b.C
+			cur.V += b.V
+			continue
+		}
+		out = append(out, store.Candle{
+			Market:     b.Market,
+			Resolution: resolution,
+			T:          start,
+			O:          b.O,
+			H:          b.H,
+			L:          b.L,
+			C:          b.C,
+			V:          b.V,
+		})
+	}
+	return out
+}
+
+// Source picks the stored resolution to resample target from: the coarsest one
+// whose bars nest exactly in target's bars. Week and month bars are built from
+// minute or day bars only, since stored weekly bars need not start on Monday.
+func Source(target Resolution, stored []string) (string, bool) {
+	span := target.Seconds()
+	if target.Unit == Month {
+		span = 86400 // month bounds fall on day bounds
+	}
+	best, bestWidth := "", int64(0)
+	for _, s := range stored {
+		r, err := ParseResolution(s)
+		if err != nil || r.Unit == Week || r.Unit == Month {
+			continue
+		}
+		w := r.Seconds()
+		if w <= span && span%w == 0 && w > bestWidth {
+			best, bestWidth = s, w
+		}
+	}
+	return best, bestWidth > 0
+}
+
+// Mismatches compares derived bars with reference bars of the same series and
+// returns the t of every bar present in both whose OHLCV differ by more than
+// relTol relative to the reference value.
+func Mismatches(derived, reference []store.Candle, relTol float64) []int64 {
+	ref := make(map[int64]store.Candle, len(reference))
+	for _, c := range reference {
+		ref[c.T] = c
+	}
+	var out []int64
+	for _, d := range derived {
+		r, ok := ref[d.T]
+		if !ok {
+			continue
+		}
+		if !near(d.O, r.O, relTol) || !near(d.H, r.H, relTol) || !near(d.L, r.L, relTol) ||
+			!near(d.C, r.C, relTol) || !near(d.V, r.V, relTol) {
+			out = append(out, d.T)
+		}
+	}
+	return out
+}
+
+func near(a, b, relTol float64) bool {
+	return math.Abs(a-b) <= relTol*math.Max(math.Abs(b), 1e-12)
+}
@@ -0,0 +1,116 @@
+package candle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+func TestRollup(t *testing.T) {
+	// 1-minute bars across two 5-minute buckets, out of order, with a gap
+	bars := []store.Candle{
+		{Market: "m", Resolution: "1", T: 360, O: 7, H: 9, L: 6, C: 8, V: 1},
+		{Market: "m", Resolution: "1", T: 60, O: 2, H: 4, L: 1, C: 3, V: 2},
+		{Market: "m", Resolution: "1", T: 0, O: 1, H: 2, L: 0.5, C: 2, V: 1},
+		{Market: "m", Resolution: "1", T: 240, O: 3, H: 5, L: 3, C: 4, V: 3},
+	}
+	got, err := Rollup(bars, "5")
+	if err != nil {
+		t.Fatalf("rollup: %v", err)
+	}
+	want := []store.Candle{
+		{Market: "m", Resolution: "5", T: 0, O: 1, H: 5, L: 0.5, C: 4, V: 6},
+		{Market: "m", Resolution: "5", T: 300, O: 7, H: 9, L: 6, C: 8, V: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d bars, want %d: %#v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("bar %d = %#v, want %#v", i, got[i], want[i])
+		}
+	}
+	if _, err := Rollup(bars, "x"); err == nil {
+		t.Fatalf("unknown resolution must fail")
+	}
+}
+
+func TestAlignDaily(t *testing.T) {
+	// 2024-05-10 13:45:00 UTC
+	if got := Align(1715348700, 86400); got != 1715299200 {
+		t.Fatalf("Align = %d, want 1715299200 (00:00 UTC)", got)
+	}
+}
+
+func TestMismatches(t *testing.T) {
+	derived := []store.Candle{{T: 0, C: 1}, {T: 60, C: 2}, {T: 120, C: 3}}
+	upstream := []store.Candle{{T: 0, C: 1}, {T: 60, C: 2.5}}
+	if got := Mismatches(derived, upstream, 1e-9); len(got) != 1 || got[0] != 60 {
+		t.Fatalf("Mismatches = %v, want [60]", got)
+	}
+}
+
+func TestParseResolution(t *testing.T) {
+	cases := map[string]Resolution{
+		"1":   {1, Minute},
+		"45":  {45, Minute},
+		"D":   {1, Day},
+		"2D":  {2, Day},
+		"24h": {1, Day},
+		"1W":  {1, Week},
+		"1w":  {1, Week},
+		"M":   {1, Month},
+		"3M":  {3, Month},
+	}
+	for in, want := range cases {
+		got, err := ParseResolution(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseResolution(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "0", "-5", "1m", "x", "1S"} {
+		if _, err := ParseResolution(bad); err == nil {
+			t.Fatalf("ParseResolution(%q) must fail", bad)
+		}
+	}
+}
+
+func TestBucketCalendar(t *testing.T) {
+	// Thursday 2024-05-16 13:45:00 UTC
+	ts := time.Date(2024, 5, 16, 13, 45, 0, 0, time.UTC).Unix()
+	if got, want := (Resolution{1, Week}).Bucket(ts), time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC).Unix(); got != want {
+		t.Fatalf("week bucket = %v, want Monday %v", time.Unix(got, 0).UTC(), time.Unix(want, 0).UTC())
+	}
+	if got, want := (Resolution{1, Month}).Bucket(ts), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Unix(); got != want {
+		t.Fatalf("month bucket = %v, want %v", time.Unix(got, 0).UTC(), time.Unix(want, 0).UTC())
+	}
+	if got, want := (Resolution{3, Month}).Bucket(ts), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC).Unix(); got != want {
+		t.Fatalf("quarter bucket = %v, want %v", time.Unix(got, 0).UTC(), time.Unix(want, 0).UTC())
+	}
+}
+
+func TestSource(t *testing.T) {
+	stored := []string{"1", "5", "15", "30", "60", "120", "240", "720", "1440", "1w"}
+	cases := map[string]string{"3": "1", "45": "15", "2D": "1440", "1W": "1440", "1M": "1440", "90": "30"}
+	for in, want := range cases {
+		r, _ := ParseResolution(in)
+		if got, ok := Source(r, stored); !ok || got != want {
+			t.Fatalf("Source(%s) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+}
+
+func TestGaps(t *testing.T) {
+	got := Gaps([]int64{0, 60, 240, 300, 480}, 60)
+	want := []Gap{{From: 120, To: 180}, {From: 360, To: 420}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("unexpected gaps: %v", got)
+	}
+	if got[0].Bars(60) != 2 || Expected(0, 480, 60) != 9 {
+		t.Fatalf("unexpected counts")
+	}
+	if Gaps([]int64{0, 60, 120}, 60) != nil {
+		t.Fatalf("expected no gaps")
+	}
+}
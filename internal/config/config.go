@@ -25,22 +25,195 @@ type MongoConf struct {
 	URI         string
 	Database    string
 	Collections MongoCollections
+	// AutoMigrate applies pending schema migrations on startup; disable it to
+	// run them explicitly with the `migrate` command.
+	AutoMigrate bool `json:",default=true"`
+}
+
+// InjectiveLimit is a budget for requests to Injective: a token bucket of RPS
+// requests per second holding up to Burst tokens, and at most InFlight
+// requests at a time. A zero value leaves that part unlimited.
+type InjectiveLimit struct {
+	RPS      float64 `json:",optional"`
+	Burst    int     `json:",optional"` // default max(1, RPS)
+	InFlight int     `json:",optional"`
+	// Priority overrides the class of an endpoint: high, normal or low. By
+	// default candle history is high, market summaries normal and config,
+	// symbols and symbol_info low.
+	Priority string `json:",optional"`
 }
 
 type InjectiveConf struct {
-	BaseURL   string
+	// BaseURL is the primary upstream; BaseURLs adds mirrors of the chart API
+	// (or an own indexer serving the same paths). With more than one upstream
+	// each request goes to the healthy one with the lowest latency and fails
+	// over to the others.
+	BaseURL   string   `json:",optional"`
+	BaseURLs  []string `json:",optional"`
 	TimeoutMs int
+	// Limit is shared by every request to Injective.
+	Limit InjectiveLimit `json:",optional"`
+	// Endpoints adds a budget per endpoint on top of Limit, keyed by the path
+	// below /api/chart/v1, e.g. spot/history or derivative/symbols.
+	Endpoints map[string]InjectiveLimit `json:",optional"`
+	Retry     InjectiveRetry
+	Breaker   InjectiveBreaker
+	Probe     InjectiveProbe
+}
+
+// InjectiveProbe checks the upstreams every IntervalSec with a GET of Path
+// when more than one is configured. An upstream is unhealthy after a failed
+// probe or UnhealthyAfter consecutive failed requests, until a probe or a
+// request succeeds again.
+type InjectiveProbe struct {
+	IntervalSec    int    `json:",default=15"`
+	TimeoutMs      int    `json:",default=3000"`
+	Path           string `json:",default=/api/chart/v1/spot/config"`
+	UnhealthyAfter int    `json:",default=3"`
+}
+
+// InjectiveRetry retries requests that failed transiently: timeouts and
+// connection errors, 429 and 5xx. The delay doubles from BaseMs up to MaxMs
+// with full jitter; a longer Retry-After from Injective is honoured.
+type InjectiveRetry struct {
+	// Max is the number of retries after the first attempt; 0 disables them.
+	Max    int `json:",default=2"`
+	BaseMs int `json:",default=200"`
+	MaxMs  int `json:",default=5000"`
+}
+
+// InjectiveBreaker opens the circuit of an endpoint after Failures
+// consecutive failed requests: requests to it fail fast for CooldownSec, then
+// a single probe decides whether it closes again.
+type InjectiveBreaker struct {
+	Enabled     bool `json:",default=true"`
+	Failures    int  `json:",default=5"`
+	CooldownSec int  `json:",default=30"`
+}
+
+// RollupConf derives the higher spot/market history resolutions from stored
+// 1-minute bars instead of fetching each resolution from Injective.
+type RollupConf struct {
+	Enabled bool `json:",optional"`
+	// Verify fetches the newest closed bars of every derived resolution from
+	// Injective and logs differences; upstream bars are never stored.
+	Verify bool `json:",optional"`
+}
+
+// GapRepairConf scans the stored history series for missing bars and re-fetches
+// only the missing windows from Injective.
+type GapRepairConf struct {
+	Enabled     bool `json:",default=true"`
+	IntervalSec int  `json:",default=600"`
+	// LookbackHours bounds how far back each run scans for gaps.
+	LookbackHours int `json:",default=24"`
+	// MaxWindows caps the gaps re-fetched per run across all series.
+	MaxWindows int `json:",default=50"`
+}
+
+// JobSchedule sets when one background job runs. Cron wins over Align and
+// Align over EverySec. Setting any of EverySec, Cron or Align replaces the
+// job's default timing; JitterSec and Disabled override it on their own.
+type JobSchedule struct {
+	EverySec  int    `json:",optional"`
+	Cron      string `json:",optional"` // 5-field cron expression, UTC
+	Align     string `json:",optional"` // run after every bar close of this resolution, e.g. "1", "60"
+	OffsetSec int    `json:",optional"` // delay after the aligned bar close
+	JitterSec int    `json:",optional"` // random delay in [0, JitterSec) per run
+	Disabled  bool   `json:",optional"`
+}
+
+// LeaderConf elects one replica through Redis to run the cron scheduler.
+type LeaderConf struct {
+	Enabled bool `json:",default=true"`
+	// TTLSec is the leader lease; a crashed leader is replaced within it.
+	TTLSec int `json:",default=10"`
+}
+
+// MarketRegistryConf tracks the markets listed in the summary_all snapshots:
+// newly listed markets get their history backfilled, delisted ones are marked
+// inactive and no longer ingested.
+type MarketRegistryConf struct {
+	Enabled bool `json:",default=true"`
+	// RemoveAfterMin is how long a market must be missing from the snapshots
+	// before it is marked removed, so a flapping snapshot removes nothing.
+	RemoveAfterMin int `json:",default=30"`
+	// BackfillDays is how far back the history of a new market is backfilled.
+	BackfillDays int `json:",default=365"`
 }
 
 type CronConf struct {
 	Enabled     bool
 	IntervalSec int
+	// LiveBars is how many of the newest bars per (market, resolution) are
+	// re-fetched and overwritten on every run, so the in-progress bar converges
+	// to its final OHLCV once it closes. 0 makes ingestion insert-only.
+	LiveBars int `json:",default=3"`
+	// FetchRetries is how many times an ingestion job retries a failed fetch on
+	// top of the retries of the Injective client (Injective.Retry), so it
+	// multiplies them; 0 leaves retrying to the client.
+	FetchRetries int `json:",default=0"`
+	// ShutdownTimeoutSec is how long shutdown waits for job runs in progress
+	// before releasing their task locks and exiting anyway.
+	ShutdownTimeoutSec int `json:",default=30"`
+	Rollup             RollupConf
+	GapRepair          GapRepairConf
+	Leader             LeaderConf
+	Markets            MarketRegistryConf
+	// Jobs overrides the schedule of jobs by name, e.g. spot_config_fetch.
+	Jobs map[string]JobSchedule `json:",optional"`
+}
+
+// RetentionPolicy decides which snapshots of one series (e.g. the summary of
+// one market at one resolution) survive pruning. A zero value disables a rule;
+// the newest KeepLatest documents are never deleted by the other rules, and
+// with both other rules off everything past the newest KeepLatest is deleted.
+type RetentionPolicy struct {
+	KeepLatest int `json:",default=10"` // always keep the newest N
+	// MaxAgeHours deletes documents superseded longer ago than this; it bounds
+	// how far back the `at` parameter can look.
+	MaxAgeHours int `json:",default=168"`
+	// HourlyAfterHours thins older documents to those current at each hour.
+	HourlyAfterHours int `json:",default=24"`
+}
+
+type RetentionConf struct {
+	Enabled     bool `json:",default=true"`
+	IntervalSec int  `json:",default=3600"`
+	SummaryAll  RetentionPolicy
+	Summary     RetentionPolicy
+	Config      RetentionPolicy
+}
+
+// ResolutionsConf overrides the bar resolutions ingested and stored, which
+// otherwise follow the supported_resolutions of the Injective chart config and
+// symbols. An empty list keeps the upstream set.
+type ResolutionsConf struct {
+	// Summary lists the summary and summary_all resolutions; Injective does not
+	// advertise them.
+	Summary    []string `json:",optional"`
+	Market     []string `json:",optional"` // spot and market history
+	Derivative []string `json:",optional"` // derivative history of every symbol
+	Exclude    []string `json:",optional"` // never ingested, even if upstream lists them
+	// RefreshSec is how long a resolved set is cached before re-reading the
+	// stored config and symbols.
+	RefreshSec int `json:",default=300"`
+}
+
+// AdminConf protects every /api/admin endpoint, both the reports and those
+// that change state such as triggering or pausing a cron job. They are refused
+// while Token is empty.
+type AdminConf struct {
+	Token string `json:",optional"` // sent as `Authorization: Bearer <Token>`
 }
 
 type Config struct {
 	rest.RestConf
-	Redis     RedisConf
-	Mongo     MongoConf
-	Injective InjectiveConf
-	Cron      CronConf
+	Redis       RedisConf
+	Mongo       MongoConf
+	Injective   InjectiveConf
+	Cron        CronConf
+	Retention   RetentionConf
+	Resolutions ResolutionsConf
+	Admin       AdminConf `json:",optional"`
 }
@@ -1,5 +1,8 @@
 package consts
 
+// Default resolutions. History resolutions follow the supported_resolutions
+// stored from Injective (internal/resolutions); these are used until the
+// config and symbols have been fetched once.
 var (
 	SupportedResolutions           = []string{"hour", "60m", "day", "24h", "week", "7days", "month", "30days"}
 	SupportedMarketResolutions     = []string{"1", "5", "15", "30", "60", "120", "240", "720", "1440"}
@@ -11,6 +14,8 @@ type MarketType string
 const (
 	MarketTypeSpot       MarketType = "spot"
 	MarketTypeDerivative MarketType = "derivative"
+	// MarketTypeMarket is the aggregated market history of spot and derivative marketIds.
+	MarketTypeMarket MarketType = "market"
 )
 
 const (
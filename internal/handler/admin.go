@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+	"github.com/biya-coin/injective-chronos-go/internal/task"
+)
+
+// requireAdmin checks the `Authorization: Bearer <Admin.Token>` header and
+// writes the error response when it does not match.
+func requireAdmin(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) bool {
+	token := ctx.Config.Admin.Token
+	if token == "" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin API disabled: Admin.Token is not configured"})
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid admin token"})
+		return false
+	}
+	return true
+}
+
+// jobError maps the errors of task.ManualRun and task.SetPaused to a status.
+func jobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, task.ErrUnknownJob):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, task.ErrNoTarget):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		logx.Errorf("admin job error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
+
+// JobTriggerHandler runs a cron job once, honouring its task lock. By default
+// the run happens in the background, is cancelled on shutdown like scheduled
+// runs, and its result is recorded in the job's runs; wait=true runs it within
+// the request and returns the result.
+// Path: /api/admin/jobs/:name/trigger, Query: market=..., resolution=..., wait=true (all optional)
+func JobTriggerHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	q := r.URL.Query()
+	name := pathvar.Vars(r)["name"]
+	run, err := task.ManualRun(ctx, name, task.Target{Market: q.Get("market"), Resolution: q.Get("resolution")})
+	if err != nil {
+		jobError(w, err)
+		return
+	}
+	if q.Get("wait") == "true" {
+		writeJSON(w, http.StatusOK, run(r.Context()))
+		return
+	}
+	ctx.Go(func(c context.Context) { run(c) })
+	writeJSON(w, http.StatusAccepted, map[string]string{"job": name, "status": "triggered"})
+}
+
+// JobPauseHandler pauses a cron job on every replica.
+// Path: /api/admin/jobs/:name/pause
+func JobPauseHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	setJobPaused(ctx, w, r, true)
+}
+
+// JobResumeHandler resumes a paused cron job.
+// Path: /api/admin/jobs/:name/resume
+func JobResumeHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	setJobPaused(ctx, w, r, false)
+}
+
+func setJobPaused(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request, paused bool) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	name := pathvar.Vars(r)["name"]
+	if err := task.SetPaused(r.Context(), ctx, name, paused); err != nil {
+		jobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"job": name, "paused": paused})
+}
@@ -2,12 +2,18 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
 
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
 	"github.com/biya-coin/injective-chronos-go/internal/logic"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
 	"github.com/biya-coin/injective-chronos-go/internal/svc"
 )
 
@@ -17,6 +23,75 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// historyError writes the error of a history lookup: 400 for a resolution
+// that is neither stored nor derivable (see Resolutions), 500 otherwise.
+func historyError(w http.ResponseWriter, name string, err error) {
+	if errors.Is(err, logic.ErrUnsupportedResolution) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	logx.Errorf("%s error: %v", name, err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
+
+// parseAt reads the optional `at` query param (unix seconds); 0 means latest.
+func parseAt(r *http.Request) (int64, error) {
+	v := r.URL.Query().Get("at")
+	if v == "" {
+		return 0, nil
+	}
+	at, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || at < 0 {
+		return 0, fmt.Errorf("invalid at %q: want unix seconds", v)
+	}
+	return at, nil
+}
+
+// parseRange reads the `from`/`to` history query params (unix seconds).
+// `to` is required and must be positive: the store treats a zero bound as open-ended,
+// so a missing or malformed `to` must not widen the query to the whole series.
+func parseRange(r *http.Request) (from, to int64, err error) {
+	q := r.URL.Query()
+	if v := q.Get("from"); v != "" {
+		if from, err = strconv.ParseInt(v, 10, 64); err != nil || from < 0 {
+			return 0, 0, fmt.Errorf("invalid from %q: want unix seconds", v)
+		}
+	}
+	v := q.Get("to")
+	if v == "" || v == "0" {
+		return 0, 0, errors.New("missing to query param")
+	}
+	if to, err = strconv.ParseInt(v, 10, 64); err != nil || to <= 0 {
+		return 0, 0, fmt.Errorf("invalid to %q: want unix seconds", v)
+	}
+	return from, to, nil
+}
+
+// marketSummaryAll serves both summary_all endpoints.
+func marketSummaryAll(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request, marketType consts.MarketType) {
+	lgc := logic.NewChartLogic(r.Context(), ctx)
+	resolution := r.URL.Query().Get("resolution")
+	if resolution == "" {
+		resolution = "24h"
+	}
+	at, err := parseAt(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	resp, err := lgc.GetMarketSummaryAll(r.Context(), marketType, resolution, at)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no summary_all snapshot at or before the requested time"})
+		return
+	}
+	if err != nil {
+		logx.Errorf("MarketSummaryAll %s error: %v", marketType, err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
 // MarketHistoryHandler returns candle history for multiple marketIDs from Mongo.
 // Query: marketIDs=... (repeatable), resolution=5, countback=100
 func MarketHistoryHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
@@ -41,7 +116,146 @@ func MarketHistoryHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *htt
 	lgc := logic.NewChartLogic(r.Context(), ctx)
 	data, err := lgc.GetMarketHistory(r.Context(), marketIDs, resolution, countback)
 	if err != nil {
-		logx.Errorf("MarketHistory error: %v", err)
+		historyError(w, "MarketHistory", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// CandleCompletenessHandler reports missing bars per stored history series.
+// Query: type=spot|derivative|market, market=... (optional, adds gap windows),
+// lookbackHours=... (optional, only the bars of the last hours)
+func CandleCompletenessHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	q := r.URL.Query()
+	var since int64
+	if v := q.Get("lookbackHours"); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || hours <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid lookbackHours"})
+			return
+		}
+		since = time.Now().Add(-time.Duration(hours) * time.Hour).Unix()
+	}
+	lgc := logic.NewChartLogic(r.Context(), ctx)
+	data, err := lgc.GetCandleCompleteness(r.Context(), consts.MarketType(q.Get("type")), q.Get("market"), since)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// SyncStateHandler lists the per-series ingestion watermarks.
+// Query: type=spot|derivative|market (optional)
+func SyncStateHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	lgc := logic.NewChartLogic(r.Context(), ctx)
+	data, err := lgc.GetSyncStates(r.Context(), consts.MarketType(r.URL.Query().Get("type")))
+	if err != nil {
+		logx.Errorf("SyncState error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// JobsHandler lists the cron jobs with their last run, failing jobs first.
+func JobsHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	lgc := logic.NewChartLogic(r.Context(), ctx)
+	data, err := lgc.GetJobs(r.Context())
+	if err != nil {
+		logx.Errorf("Jobs error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// JobRunsHandler returns the newest runs of one cron job.
+// Path: /api/admin/jobs/:name/runs, Query: limit=50 (optional, max 500)
+func JobRunsHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid limit %q", v)})
+			return
+		}
+		limit = n
+	}
+	lgc := logic.NewChartLogic(r.Context(), ctx)
+	data, err := lgc.GetJobRuns(r.Context(), pathvar.Vars(r)["name"], limit)
+	if err != nil {
+		logx.Errorf("JobRuns error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// MarketsHandler lists the market registry.
+// Query: type=spot|derivative (optional), active=true (optional, drops delisted markets)
+func MarketsHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	q := r.URL.Query()
+	lgc := logic.NewChartLogic(r.Context(), ctx)
+	data, err := lgc.GetMarkets(r.Context(), consts.MarketType(q.Get("type")), q.Get("active") == "true")
+	if err != nil {
+		logx.Errorf("Markets error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// MarketEventsHandler returns the newest markets added to or removed from the registry.
+// Query: type=spot|derivative (optional), limit=50 (optional, max 500)
+func MarketEventsHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	q := r.URL.Query()
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid limit %q", v)})
+			return
+		}
+		limit = n
+	}
+	lgc := logic.NewChartLogic(r.Context(), ctx)
+	data, err := lgc.GetMarketEvents(r.Context(), consts.MarketType(q.Get("type")), limit)
+	if err != nil {
+		logx.Errorf("MarketEvents error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// LeaderHandler reports which replica currently runs the cron scheduler.
+func LeaderHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(ctx, w, r) {
+		return
+	}
+	lgc := logic.NewChartLogic(r.Context(), ctx)
+	data, err := lgc.GetLeader(r.Context())
+	if err != nil {
+		logx.Errorf("Leader error: %v", err)
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
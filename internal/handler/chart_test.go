@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		query    string
+		from, to int64
+		ok       bool
+	}{
+		{"from=100&to=200", 100, 200, true},
+		{"to=200", 0, 200, true},
+		{"from=0&to=200", 0, 200, true},
+		{"from=100", 0, 0, false},
+		{"from=100&to=0", 0, 0, false},
+		{"from=100&to=-5", 0, 0, false},
+		{"from=100&to=abc", 0, 0, false},
+		{"from=abc&to=200", 0, 0, false},
+		{"from=-1&to=200", 0, 0, false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/history?"+c.query, nil)
+		from, to, err := parseRange(r)
+		if (err == nil) != c.ok || from != c.from || to != c.to {
+			t.Fatalf("%s: got %d-%d err=%v", c.query, from, to, err)
+		}
+	}
+}
+
+func TestHistoryHandlersRejectUnboundedRange(t *testing.T) {
+	// 非法的 to 必须在访问存储前返回 400，否则会被当作无上界查询整个序列
+	for _, h := range []func(http.ResponseWriter, *http.Request){
+		func(w http.ResponseWriter, r *http.Request) { SpotMarketHistoryHandler(nil, w, r) },
+		func(w http.ResponseWriter, r *http.Request) { DerivativeMarketHistoryHandler(nil, w, r) },
+	} {
+		for _, q := range []string{"to=0", "to=abc", "to=-1"} {
+			w := httptest.NewRecorder()
+			h(w, httptest.NewRequest(http.MethodGet, "/history?marketId=m&symbol=m&resolution=1&from=100&"+q, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("%s: status %d, want 400", q, w.Code)
+			}
+		}
+	}
+}
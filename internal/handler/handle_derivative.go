@@ -11,19 +11,9 @@ import (
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
+// DerivativeMarketSummaryAllHandler returns the derivative market list; Query: resolution=24h, at=<unix> (optional).
 func DerivativeMarketSummaryAllHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
-	lgc := logic.NewChartLogic(r.Context(), ctx)
-	resolution := r.URL.Query().Get("resolution")
-	if resolution == "" {
-		resolution = "24h"
-	}
-	resp, err := lgc.GetMarketSummaryAll(r.Context(), consts.MarketTypeDerivative, resolution)
-	if err != nil {
-		logx.Errorf("DerivativeMarketSummaryAll error: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-	writeJSON(w, http.StatusOK, resp)
+	marketSummaryAll(ctx, w, r, consts.MarketTypeDerivative)
 }
 
 func DerivativeMarketSummaryHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
@@ -67,18 +57,11 @@ func DerivativeMarketHistoryHandler(ctx *svc.ServiceContext, w http.ResponseWrit
 		}
 	}
 
-	from := q.Get("from")
-	var fromInt int64 = 0
-	if from != "" && from != "0" {
-		fromInt, _ = strconv.ParseInt(from, 10, 64)
-	}
-	to := q.Get("to")
-	if to == "" || to == "0" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing to  query param"})
+	fromInt, toInt, err := parseRange(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	var toInt int64 = 0
-	toInt, _ = strconv.ParseInt(to, 10, 64)
 	if symbol == "" {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing symbol query param"})
 		return
@@ -86,8 +69,7 @@ func DerivativeMarketHistoryHandler(ctx *svc.ServiceContext, w http.ResponseWrit
 	lgc := logic.NewChartLogic(r.Context(), ctx)
 	data, err := lgc.GetDerivativeHistory(r.Context(), symbol, resolution, fromInt, toInt, countback)
 	if err != nil {
-		logx.Errorf("GetMarketHistoryDerivative error: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		historyError(w, "GetMarketHistoryDerivative", err)
 		return
 	}
 	writeJSON(w, http.StatusOK, model.DerivativeHistoryResponse{
@@ -32,18 +32,11 @@ func SpotMarketHistoryHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r
 	if resolution == "" {
 		resolution = "1"
 	}
-	from := q.Get("from")
-	var fromInt int64 = 0
-	if from != "" && from != "0" {
-		fromInt, _ = strconv.ParseInt(from, 10, 64)
-	}
-	to := q.Get("to")
-	if to == "" || to == "0" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing to"})
+	fromInt, toInt, err := parseRange(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	var toInt int64 = 0
-	toInt, _ = strconv.ParseInt(to, 10, 64)
 	// countback optional
 	countback := 0
 	if v := q.Get("countback"); v != "" {
@@ -58,8 +51,7 @@ func SpotMarketHistoryHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r
 	lgc := logic.NewChartLogic(r.Context(), ctx)
 	data, err := lgc.GetMarketHistorySpot(r.Context(), marketId, resolution, countback, fromInt, toInt)
 	if err != nil {
-		logx.Errorf("SpotMarketHistory error: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		historyError(w, "SpotMarketHistory", err)
 		return
 	}
 	// pack response
@@ -89,19 +81,9 @@ func SpotMarketSummaryHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// SpotMarketSummaryAllHandler returns the spot market list; Query: resolution=24h, at=<unix> (optional).
 func SpotMarketSummaryAllHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
-	lgc := logic.NewChartLogic(r.Context(), ctx)
-	resolution := r.URL.Query().Get("resolution")
-	if resolution == "" {
-		resolution = "24h"
-	}
-	resp, err := lgc.GetMarketSummaryAll(r.Context(), consts.MarketTypeSpot, resolution)
-	if err != nil {
-		logx.Errorf("SpotMarketSummaryAll error: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
-		return
-	}
-	writeJSON(w, http.StatusOK, resp)
+	marketSummaryAll(ctx, w, r, consts.MarketTypeSpot)
 }
 
 func SpotSymbolInfoHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
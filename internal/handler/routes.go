@@ -7,12 +7,32 @@ import (
 	"github.com/zeromicro/go-zero/rest"
 
 	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
 	"github.com/biya-coin/injective-chronos-go/internal/svc"
 )
 
-// health check
-func HealthHandler(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]interface{}{"ts": time.Now().Unix()})
+// health check; status is degraded while the circuit of an Injective endpoint
+// is not closed or an upstream is unhealthy, but the service keeps answering
+// from storage.
+func HealthHandler(ctx *svc.ServiceContext, w http.ResponseWriter, r *http.Request) {
+	status := "ok"
+	circuits := map[string]string{}
+	upstreams := []injective.UpstreamStatus{}
+	if ctx.Injective != nil {
+		circuits = ctx.Injective.BreakerStates()
+		upstreams = ctx.Injective.Upstreams()
+	}
+	for _, state := range circuits {
+		if state != injective.CircuitClosed {
+			status = "degraded"
+		}
+	}
+	for _, up := range upstreams {
+		if !up.Healthy {
+			status = "degraded"
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ts": time.Now().Unix(), "status": status, "injective": circuits, "upstreams": upstreams})
 }
 
 func RegisterHandlers(server *rest.Server, ctx *svc.ServiceContext) {
@@ -122,4 +142,76 @@ func RegisterHandlers(server *rest.Server, ctx *svc.ServiceContext) {
 			MarketHistoryHandler(ctx, w, r)
 		},
 	})
+
+	// admin
+	server.AddRoute(rest.Route{
+		Method: http.MethodGet,
+		Path:   "/api/admin/candles/completeness",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			CandleCompletenessHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodGet,
+		Path:   "/api/admin/sync_state",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			SyncStateHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodGet,
+		Path:   "/api/admin/leader",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			LeaderHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodGet,
+		Path:   "/api/admin/markets",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			MarketsHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodGet,
+		Path:   "/api/admin/markets/events",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			MarketEventsHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodGet,
+		Path:   "/api/admin/jobs",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			JobsHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodGet,
+		Path:   "/api/admin/jobs/:name/runs",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			JobRunsHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodPost,
+		Path:   "/api/admin/jobs/:name/trigger",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			JobTriggerHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodPost,
+		Path:   "/api/admin/jobs/:name/pause",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			JobPauseHandler(ctx, w, r)
+		},
+	})
+	server.AddRoute(rest.Route{
+		Method: http.MethodPost,
+		Path:   "/api/admin/jobs/:name/resume",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			JobResumeHandler(ctx, w, r)
+		},
+	})
 }
@@ -0,0 +1,166 @@
+package injective
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/metric"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+)
+
+var breakerState = metric.NewGaugeVec(&metric.GaugeVecOpts{
+	Namespace: "chronos",
+	Subsystem: "injective",
+	Name:      "breaker_state",
+	Help:      "circuit breaker state per Injective endpoint: 0 closed, 1 half-open, 2 open.",
+	Labels:    []string{"endpoint"},
+})
+
+// ErrCircuitOpen is returned without contacting Injective while the circuit
+// of an endpoint is open.
+var ErrCircuitOpen = errors.New("injective circuit open")
+
+// Circuit states.
+const (
+	CircuitClosed   = "closed"
+	CircuitHalfOpen = "half-open"
+	CircuitOpen     = "open"
+)
+
+// breakers holds one circuit per endpoint, created on first use. A nil
+// breakers (Breaker.Enabled=false) admits everything.
+type breakers struct {
+	failures int
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    string
+	failures int       // consecutive failures while closed
+	openedAt time.Time // when the circuit last opened
+	probing  bool      // a half-open probe is in flight
+}
+
+func newBreakers(c config.InjectiveBreaker) *breakers {
+	if !c.Enabled {
+		return nil
+	}
+	return &breakers{
+		failures: max(c.Failures, 1),
+		cooldown: time.Duration(c.CooldownSec) * time.Second,
+		circuits: map[string]*circuit{},
+	}
+}
+
+// allow reports whether a request to endpoint may be sent. Once the cooldown
+// of an open circuit has passed, the first caller is let through as the probe
+// and the circuit turns half-open until done reports its outcome.
+func (b *breakers) allow(endpoint string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.get(endpoint)
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < b.cooldown {
+			return false
+		}
+		b.set(endpoint, c, CircuitHalfOpen)
+		c.probing = true
+		return true
+	case CircuitHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+	}
+	return true
+}
+
+// done records the outcome of a request let through by allow.
+func (b *breakers) done(endpoint string, ok bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.get(endpoint)
+	c.probing = false
+	if ok {
+		c.failures = 0
+		if c.state != CircuitClosed {
+			logx.Infof("injective %s: circuit closed", endpoint)
+			b.set(endpoint, c, CircuitClosed)
+		}
+		return
+	}
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= b.failures {
+		if c.state != CircuitOpen {
+			logx.Errorf("injective %s: circuit open after %d consecutive failures", endpoint, c.failures)
+		}
+		c.openedAt = time.Now()
+		b.set(endpoint, c, CircuitOpen)
+	}
+}
+
+// cancel ends a request let through by allow without an outcome, e.g. one
+// cancelled by its caller.
+func (b *breakers) cancel(endpoint string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.get(endpoint).probing = false
+}
+
+// states returns the state of every endpoint requested so far.
+func (b *breakers) states() map[string]string {
+	out := map[string]string{}
+	if b == nil {
+		return out
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, c := range b.circuits {
+		out[name] = c.state
+	}
+	return out
+}
+
+func (b *breakers) get(endpoint string) *circuit {
+	c, ok := b.circuits[endpoint]
+	if !ok {
+		c = &circuit{state: CircuitClosed}
+		b.circuits[endpoint] = c
+		breakerState.Set(0, endpoint)
+	}
+	return c
+}
+
+func (b *breakers) set(endpoint string, c *circuit, state string) {
+	c.state = state
+	v := 0.0
+	switch state {
+	case CircuitHalfOpen:
+		v = 1
+	case CircuitOpen:
+		v = 2
+	}
+	breakerState.Set(v, endpoint)
+}
+
+// BreakerStates returns the circuit state of every Injective endpoint
+// requested so far, keyed by endpoint name (e.g. spot/history).
+func (c *Client) BreakerStates() map[string]string {
+	return c.breakers.states()
+}
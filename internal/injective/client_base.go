@@ -6,11 +6,21 @@ import (
 	"github.com/biya-coin/injective-chronos-go/internal/config"
 )
 
+// Client calls the Injective chart API on the upstreams cfg.BaseURL and
+// cfg.BaseURLs. Every request goes through the request budget of cfg.Limit and
+// cfg.Endpoints and the circuit breaker of its endpoint, so one Client should
+// be shared by everything that calls Injective (svc.ServiceContext.Injective).
 type Client struct {
 	cfg        config.InjectiveConf
 	httpClient *http.Client
+	limits     *limits
+	breakers   *breakers
+	upstreams  *upstreams
 }
 
 func NewClient(cfg config.InjectiveConf, hc *http.Client) *Client {
-	return &Client{cfg: cfg, httpClient: hc}
+	ups := newUpstreams(cfg)
+	// 各方法以 BaseURL 拼出请求地址，发送时再改写到选中的上游
+	cfg.BaseURL = ups.primary()
+	return &Client{cfg: cfg, httpClient: hc, limits: newLimits(cfg), breakers: newBreakers(cfg.Breaker), upstreams: ups}
 }
@@ -23,7 +23,7 @@ func (c *Client) DerivativeMarketSummaryAll(ctx context.Context, resolution stri
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +53,7 @@ func (c *Client) DerivativeMarketSummaryAtResolution(ctx context.Context, market
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +74,7 @@ func (c *Client) DerivativeConfig(ctx context.Context) (*model.ChartDerivativeCo
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +100,7 @@ func (c *Client) DerivativeSymbolInfo(ctx context.Context, group string) (*model
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +124,7 @@ func (c *Client) DerivativeSymbols(ctx context.Context, symbol string) (*model.D
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +140,14 @@ func (c *Client) DerivativeSymbols(ctx context.Context, symbol string) (*model.D
 	return &out, nil
 }
 
+// DerivativeHistory fetches derivative candles from `from` up to now.
 func (c *Client) DerivativeHistory(ctx context.Context, symbol string, resolution string, from int64) (*model.DerivativeHistory, error) {
+	return c.DerivativeHistoryRange(ctx, symbol, resolution, from, time.Now().Unix())
+}
+
+// DerivativeHistoryRange fetches derivative candles with from <= t <= to; a zero
+// from leaves the start to the upstream default.
+func (c *Client) DerivativeHistoryRange(ctx context.Context, symbol string, resolution string, from int64, to int64) (*model.DerivativeHistory, error) {
 	u := fmt.Sprintf("%s%s", c.cfg.BaseURL, consts.DerivativeHistoryPath)
 	q := url.Values{}
 	q.Set("symbol", symbol)
@@ -148,13 +155,12 @@ func (c *Client) DerivativeHistory(ctx context.Context, symbol string, resolutio
 	if from != 0 {
 		q.Set("from", fmt.Sprintf("%d", from))
 	}
-	to := time.Now().Unix()
 	q.Set("to", fmt.Sprintf("%d", to))
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"?"+q.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
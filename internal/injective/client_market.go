@@ -46,7 +46,7 @@ func (c *Client) MarketHistory(ctx context.Context, marketIDs []string, resoluti
 		logx.Errorf("MarketHistory new request error: %v", err)
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		logx.Errorf("MarketHistory do request error: %v", err)
 		return nil, err
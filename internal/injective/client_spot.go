@@ -19,7 +19,7 @@ func (c *Client) SpotConfig(ctx context.Context) (*model.ChartSpotConfig, error)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +45,7 @@ func (c *Client) SpotMarketSummaryAll(ctx context.Context, resolution string) ([
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +71,7 @@ func (c *Client) SpotMarketSummary(ctx context.Context, market string) (*model.S
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +101,7 @@ func (c *Client) SpotMarketSummaryAtResolution(ctx context.Context, market strin
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -153,7 +153,7 @@ func (c *Client) SpotMarketHistory(ctx context.Context, from int64, to int64, ma
 		logx.Errorf("SpotMarketHistory new request error: %v", err)
 		return model.SpotMarketHistory{}, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		logx.Errorf("SpotMarketHistory do request error: %v", err)
 		return model.SpotMarketHistory{}, err
@@ -182,7 +182,7 @@ func (c *Client) SpotSymbolInfo(ctx context.Context, group string) (*model.SpotS
 		logx.Errorf("SpotSymbolInfo new request error: %v", err)
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		logx.Errorf("SpotSymbolInfo do request error: %v", err)
 		return nil, err
@@ -214,7 +214,7 @@ func (c *Client) SpotSymbols(ctx context.Context, symbol string) (*model.SpotSym
 		logx.Errorf("SpotSymbols new request error: %v", err)
 		return nil, err
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		logx.Errorf("SpotSymbols do request error: %v", err)
 		return nil, err
@@ -162,3 +162,24 @@ func TestClient_DerivativeConfig(t *testing.T) {
 		t.Fatalf("missing supported_resolutions: %#v", out)
 	}
 }
+
+func TestClient_DerivativeHistoryRange(t *testing.T) {
+	resp := model.DerivativeHistory{T: []int64{60}, O: []float64{1}, H: []float64{1}, L: []float64{1}, C: []float64{1}, V: []float64{1}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != consts.DerivativeHistoryPath || q.Get("symbol") != "BTC/USDT PERP" || q.Get("from") != "60" || q.Get("to") != "120" {
+			t.Fatalf("unexpected request %s", r.URL.String())
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	c := NewClient(config.InjectiveConf{BaseURL: ts.URL}, ts.Client())
+	out, err := c.DerivativeHistoryRange(context.Background(), "BTC/USDT PERP", "1", 60, 120)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(out.T) != 1 || out.T[0] != 60 {
+		t.Fatalf("unexpected out: %#v", out)
+	}
+}
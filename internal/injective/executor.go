@@ -0,0 +1,196 @@
+package injective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/metric"
+)
+
+var requestRetries = metric.NewCounterVec(&metric.CounterVecOpts{
+	Namespace: "chronos",
+	Subsystem: "injective",
+	Name:      "retries_total",
+	Help:      "requests to Injective retried, by endpoint and reason.",
+	Labels:    []string{"endpoint", "reason"},
+})
+
+// do is the request executor shared by every Client method. It fails fast
+// while the circuit of the endpoint is open and sends each attempt within the
+// request budget to the best upstream. A transient failure fails over to an
+// upstream not tried yet; once none is left it is retried with backoff. A
+// response that is not retried, or still fails after the last retry, is
+// returned as is and reported by the caller.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	endpoint := endpointName(req.URL.Path)
+	if !c.breakers.allow(endpoint) {
+		return nil, fmt.Errorf("%s: %w", endpoint, ErrCircuitOpen)
+	}
+	tried := map[*upstream]bool{}
+	for retries := 0; ; {
+		up := c.upstreams.pick(tried)
+		tried[up] = true
+		resp, latency, err := c.send(up.target(req), endpoint)
+		if err != nil && ctx.Err() != nil {
+			// 调用方取消，不计入熔断与上游健康
+			c.breakers.cancel(endpoint)
+			return nil, err
+		}
+		reason := retryReason(resp, err)
+		// 429 说明上游在线，只是限流
+		c.upstreams.observe(up, latency, reason == "" || reason == "429")
+		if reason == "" {
+			c.breakers.done(endpoint, true)
+			return resp, err
+		}
+		if next := c.upstreams.pick(tried); next != nil && c.upstreams.healthy(next) {
+			discard(resp)
+			requestRetries.Inc(endpoint, "failover")
+			logx.WithContext(ctx).Infof("injective %s: %s from %s, failing over to %s", endpoint, reason, up.name, next.name)
+			if req, err = rewind(req); err != nil {
+				c.breakers.cancel(endpoint)
+				return nil, err
+			}
+			continue
+		}
+		wait := c.backoff(retries, resp)
+		if retries >= c.cfg.Retry.Max || !fits(ctx, wait) {
+			c.breakers.done(endpoint, false)
+			return resp, err
+		}
+		discard(resp)
+		retries++
+		requestRetries.Inc(endpoint, reason)
+		logx.WithContext(ctx).Infof("injective %s: %s, retry %d/%d in %s", endpoint, reason, retries, c.cfg.Retry.Max, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			c.breakers.cancel(endpoint)
+			return nil, ctx.Err()
+		}
+		if req, err = rewind(req); err != nil {
+			c.breakers.cancel(endpoint)
+			return nil, err
+		}
+		clear(tried)
+	}
+}
+
+// send makes one attempt within the request budget and returns the time to
+// the response headers. The in-flight slot is held until the response body
+// is closed.
+func (c *Client) send(req *http.Request, endpoint string) (*http.Response, time.Duration, error) {
+	release, err := c.limits.acquire(req.Context(), endpoint)
+	if err != nil {
+		return nil, 0, err
+	}
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		release()
+		return nil, latency, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, latency, nil
+}
+
+// discard drains and closes the body of a response that is not returned.
+func discard(resp *http.Response) {
+	if resp != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// retryReason classifies a failed attempt as transient, returning the reason
+// it is retried, or "" if it succeeded or retrying cannot help.
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			return "timeout"
+		}
+		return "connection"
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "429"
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return "5xx"
+	}
+	return ""
+}
+
+// backoff returns the delay before retry attempt+1: exponential from BaseMs
+// up to MaxMs with full jitter, or the Retry-After of resp if that is longer.
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	base := time.Duration(max(c.cfg.Retry.BaseMs, 1)) * time.Millisecond
+	ceil := max(time.Duration(c.cfg.Retry.MaxMs)*time.Millisecond, base)
+	d := ceil
+	if attempt < 30 && base<<attempt < ceil {
+		d = base << attempt
+	}
+	wait := time.Duration(rand.Int63n(int64(d))) + time.Millisecond
+	if resp != nil {
+		if ra := retryAfter(resp.Header.Get("Retry-After")); ra > wait {
+			wait = ra
+		}
+	}
+	return wait
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(s, 0)) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// fits reports whether waiting d still leaves time before the deadline of ctx.
+func fits(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
+
+// rewind returns a copy of req to send again, with a fresh body if it has one.
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("injective %s: request body cannot be resent", req.URL.Path)
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+	return next, nil
+}
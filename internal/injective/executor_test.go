@@ -0,0 +1,105 @@
+package injective
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+)
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte(`{"supported_resolutions":["1"]}`))
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(config.InjectiveConf{BaseURL: ts.URL, Retry: config.InjectiveRetry{Max: 2, BaseMs: 1, MaxMs: 5}}, ts.Client())
+	if _, err := c.SpotConfig(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("calls = %d, want 3", n)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	c := NewClient(config.InjectiveConf{BaseURL: ts.URL, Retry: config.InjectiveRetry{Max: 2, BaseMs: 1, MaxMs: 5}}, ts.Client())
+	if _, err := c.SpotConfig(context.Background()); err == nil {
+		t.Fatalf("expected err for 400")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("calls = %d, want 1", n)
+	}
+}
+
+func TestClientBreakerOpensAndProbes(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(config.InjectiveConf{BaseURL: ts.URL, Breaker: config.InjectiveBreaker{Enabled: true, Failures: 2}}, ts.Client())
+	for i := 0; i < 2; i++ {
+		if _, err := c.SpotConfig(context.Background()); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: err = %v, want http error", i, err)
+		}
+	}
+	endpoint := endpointName(consts.SpotConfigPath)
+	if state := c.BreakerStates()[endpoint]; state != CircuitOpen {
+		t.Fatalf("state = %s, want open", state)
+	}
+
+	// 冷却期为 0：下一次请求作为探测放行，成功后关闭
+	healthy.Store(true)
+	if _, err := c.SpotConfig(context.Background()); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if state := c.BreakerStates()[endpoint]; state != CircuitClosed {
+		t.Fatalf("state = %s, want closed", state)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("calls = %d, want 3", n)
+	}
+}
+
+func TestBreakerFailsFastWhileOpen(t *testing.T) {
+	b := newBreakers(config.InjectiveBreaker{Enabled: true, Failures: 1, CooldownSec: 60})
+	if !b.allow("spot/history") {
+		t.Fatalf("closed circuit rejected a request")
+	}
+	b.done("spot/history", false)
+	if b.allow("spot/history") {
+		t.Fatalf("open circuit admitted a request")
+	}
+	if !b.allow("spot/config") {
+		t.Fatalf("circuit of another endpoint rejected a request")
+	}
+}
@@ -0,0 +1,249 @@
+package injective
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/metric"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+)
+
+var limiterWait = metric.NewHistogramVec(&metric.HistogramVecOpts{
+	Namespace: "chronos",
+	Subsystem: "injective",
+	Name:      "limiter_wait_ms",
+	Help:      "time requests to Injective waited for the request budget, in milliseconds.",
+	Labels:    []string{"endpoint", "priority"},
+	Buckets:   []float64{1, 10, 50, 250, 1000, 5000, 30000},
+})
+
+// Priority orders requests waiting for the request budget: a waiting request
+// of a higher class is always let through before a lower one.
+type Priority int
+
+const (
+	PriorityLow    Priority = iota // config, symbols and symbol_info refreshes
+	PriorityNormal                 // market summaries
+	PriorityHigh                   // candle history
+	numPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	}
+	return "low"
+}
+
+// apiPrefix precedes every endpoint path; endpoint names are what follows it.
+const apiPrefix = "/api/chart/v1/"
+
+// endpointName returns the path of an endpoint below apiPrefix, e.g.
+// spot/history, as used in InjectiveConf.Endpoints.
+func endpointName(path string) string {
+	if i := strings.Index(path, apiPrefix); i >= 0 {
+		return path[i+len(apiPrefix):]
+	}
+	return strings.TrimPrefix(path, "/")
+}
+
+// defaultPriority lets candle fetches win over summaries, and both over
+// metadata refreshes.
+func defaultPriority(endpoint string) Priority {
+	switch {
+	case strings.HasSuffix(endpoint, "/history"):
+		return PriorityHigh
+	case strings.Contains(endpoint, "market_summary"):
+		return PriorityNormal
+	}
+	return PriorityLow
+}
+
+// limits is the request budget of a Client: the shared Limit plus one limiter
+// per configured endpoint.
+type limits struct {
+	global    *limiter
+	endpoints map[string]*limiter
+	priority  map[string]Priority
+}
+
+func newLimits(cfg config.InjectiveConf) *limits {
+	l := &limits{global: newLimiter(cfg.Limit), endpoints: map[string]*limiter{}, priority: map[string]Priority{}}
+	for name, el := range cfg.Endpoints {
+		name = strings.Trim(name, "/")
+		if lim := newLimiter(el); lim != nil {
+			l.endpoints[name] = lim
+		}
+		switch el.Priority {
+		case "":
+		case "high":
+			l.priority[name] = PriorityHigh
+		case "normal":
+			l.priority[name] = PriorityNormal
+		case "low":
+			l.priority[name] = PriorityLow
+		default:
+			logx.Errorf("injective endpoint %s: unknown priority %q, using the default", name, el.Priority)
+		}
+	}
+	return l
+}
+
+// acquire waits until a request to endpoint fits the budget; the returned
+// function gives its in-flight slots back.
+func (l *limits) acquire(ctx context.Context, endpoint string) (func(), error) {
+	p, ok := l.priority[endpoint]
+	if !ok {
+		p = defaultPriority(endpoint)
+	}
+	start := time.Now()
+	ep := l.endpoints[endpoint]
+	if err := ep.acquire(ctx, p); err != nil {
+		return nil, err
+	}
+	if err := l.global.acquire(ctx, p); err != nil {
+		ep.release()
+		return nil, err
+	}
+	limiterWait.Observe(time.Since(start).Milliseconds(), endpoint, p.String())
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.global.release()
+			ep.release()
+		})
+	}, nil
+}
+
+// limiter is a token bucket combined with an in-flight cap. Waiters are
+// queued per Priority and served highest class first, FIFO within a class.
+// A nil limiter admits everything.
+type limiter struct {
+	rate  float64 // tokens per second; 0 = no rate limit
+	burst float64
+	max   int // in-flight cap; 0 = none
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	inFlight int
+	queue    [numPriorities][]chan struct{}
+	timer    *time.Timer // pending dispatch once the next token is due
+}
+
+func newLimiter(c config.InjectiveLimit) *limiter {
+	if c.RPS <= 0 && c.InFlight <= 0 {
+		return nil
+	}
+	burst := float64(c.Burst)
+	if burst <= 0 {
+		burst = max(1, c.RPS)
+	}
+	return &limiter{rate: c.RPS, burst: burst, max: c.InFlight, tokens: burst, last: time.Now()}
+}
+
+func (l *limiter) acquire(ctx context.Context, p Priority) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if !l.waiting(p) && l.take() {
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	l.queue[p] = append(l.queue[p], ready)
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+	l.mu.Lock()
+	for i, ch := range l.queue[p] {
+		if ch == ready {
+			l.queue[p] = append(l.queue[p][:i], l.queue[p][i+1:]...)
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	l.mu.Unlock()
+	// 取消的同时已被放行，归还名额
+	l.release()
+	return ctx.Err()
+}
+
+func (l *limiter) release() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max > 0 {
+		l.inFlight--
+	}
+	l.dispatch()
+}
+
+// waiting reports whether a request of class p or higher is queued.
+func (l *limiter) waiting(p Priority) bool {
+	for q := p; q < numPriorities; q++ {
+		if len(l.queue[q]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// take consumes a token and an in-flight slot if both are available.
+func (l *limiter) take() bool {
+	if l.max > 0 && l.inFlight >= l.max {
+		return false
+	}
+	if l.rate > 0 {
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens < 1 {
+			return false
+		}
+		l.tokens--
+	}
+	if l.max > 0 {
+		l.inFlight++
+	}
+	return true
+}
+
+// dispatch lets queued requests through, highest class first, while the
+// budget allows. If only tokens are missing it schedules itself for when the
+// next one is due; a full in-flight cap is re-checked on release.
+func (l *limiter) dispatch() {
+	for p := numPriorities - 1; p >= 0; p-- {
+		for len(l.queue[p]) > 0 {
+			if !l.take() {
+				if l.timer == nil && l.rate > 0 && (l.max == 0 || l.inFlight < l.max) {
+					wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+					l.timer = time.AfterFunc(wait, func() {
+						l.mu.Lock()
+						defer l.mu.Unlock()
+						l.timer = nil
+						l.dispatch()
+					})
+				}
+				return
+			}
+			close(l.queue[p][0])
+			l.queue[p] = l.queue[p][1:]
+		}
+	}
+}
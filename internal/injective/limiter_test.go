@@ -0,0 +1,94 @@
+package injective
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+)
+
+func TestLimiterServesHigherPriorityFirst(t *testing.T) {
+	l := newLimiter(config.InjectiveLimit{InFlight: 1})
+	if err := l.acquire(context.Background(), PriorityLow); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	order := make(chan Priority, 2)
+	wait := func(p Priority) {
+		if err := l.acquire(context.Background(), p); err != nil {
+			t.Errorf("acquire %s: %v", p, err)
+			return
+		}
+		order <- p
+		l.release()
+	}
+	go wait(PriorityLow)
+	waitQueued(t, l, PriorityLow)
+	go wait(PriorityHigh)
+	waitQueued(t, l, PriorityHigh)
+
+	l.release()
+	if first, second := <-order, <-order; first != PriorityHigh || second != PriorityLow {
+		t.Fatalf("order = %s, %s, want high, low", first, second)
+	}
+}
+
+func TestLimiterCancelWhileQueued(t *testing.T) {
+	l := newLimiter(config.InjectiveLimit{InFlight: 1})
+	if err := l.acquire(context.Background(), PriorityHigh); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, PriorityHigh); err == nil {
+		t.Fatalf("acquire beyond the in-flight cap succeeded")
+	}
+	l.release()
+	if err := l.acquire(context.Background(), PriorityLow); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	l := newLimiter(config.InjectiveLimit{RPS: 50, Burst: 1})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.acquire(context.Background(), PriorityNormal); err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+	}
+	// 首个请求消耗 burst，其后每 20ms 一个
+	if d := time.Since(start); d < 35*time.Millisecond {
+		t.Fatalf("3 requests at 50 rps took %v", d)
+	}
+}
+
+func TestDefaultPriority(t *testing.T) {
+	cases := map[string]Priority{
+		endpointName(consts.SpotHistoryPath):       PriorityHigh,
+		endpointName(consts.DerivativeSummaryPath): PriorityNormal,
+		endpointName(consts.SpotConfigPath):        PriorityLow,
+	}
+	for ep, want := range cases {
+		if got := defaultPriority(ep); got != want {
+			t.Fatalf("defaultPriority(%s) = %s, want %s", ep, got, want)
+		}
+	}
+}
+
+func waitQueued(t *testing.T, l *limiter, p Priority) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mu.Lock()
+		n := len(l.queue[p])
+		l.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no %s request queued", p)
+}
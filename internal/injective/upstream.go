@@ -0,0 +1,268 @@
+package injective
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/metric"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+)
+
+var (
+	upstreamHealthy = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: "chronos",
+		Subsystem: "injective",
+		Name:      "upstream_healthy",
+		Help:      "whether an Injective upstream is considered healthy (1) or not (0).",
+		Labels:    []string{"upstream"},
+	})
+	upstreamLatency = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: "chronos",
+		Subsystem: "injective",
+		Name:      "upstream_latency_ms",
+		Help:      "smoothed response latency of an Injective upstream, in milliseconds.",
+		Labels:    []string{"upstream"},
+	})
+)
+
+// UpstreamStatus is the health of one upstream as seen by the Client.
+type UpstreamStatus struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	LatencyMs int64     `json:"latencyMs"`
+	Failures  int       `json:"failures"` // consecutive failed requests
+	CheckedAt time.Time `json:"checkedAt,omitempty"`
+}
+
+type upstream struct {
+	name string // base URL as configured
+	base *url.URL
+
+	// guarded by upstreams.mu
+	healthy  bool
+	latency  time.Duration // moving average; 0 until measured
+	failures int
+	checked  time.Time // last probe
+}
+
+// upstreams are the base URLs of a Client, primary first.
+type upstreams struct {
+	list           []*upstream
+	unhealthyAfter int
+
+	mu sync.Mutex
+}
+
+func newUpstreams(cfg config.InjectiveConf) *upstreams {
+	u := &upstreams{unhealthyAfter: max(cfg.Probe.UnhealthyAfter, 1)}
+	seen := map[string]bool{}
+	for _, raw := range append([]string{cfg.BaseURL}, cfg.BaseURLs...) {
+		raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+		if raw == "" || seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		base, err := url.Parse(raw)
+		if err != nil || base.Host == "" {
+			logx.Errorf("injective upstream %q: invalid base URL, skipped", raw)
+			continue
+		}
+		u.list = append(u.list, &upstream{name: raw, base: base, healthy: true})
+		upstreamHealthy.Set(1, raw)
+	}
+	return u
+}
+
+// primary returns the first upstream's base URL, or "" without upstreams.
+func (u *upstreams) primary() string {
+	if len(u.list) == 0 {
+		return ""
+	}
+	return u.list[0].name
+}
+
+// pick returns the upstream for the next attempt of a request: among those
+// not tried yet, a healthy one before an unhealthy one, then the lowest
+// latency, then configuration order. It returns nil once all were tried.
+func (u *upstreams) pick(tried map[*upstream]bool) *upstream {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var best *upstream
+	for _, up := range u.list {
+		if tried[up] {
+			continue
+		}
+		if best == nil || up.healthy && !best.healthy || up.healthy == best.healthy && up.latency < best.latency {
+			best = up
+		}
+	}
+	return best
+}
+
+// healthy reports whether up is currently considered healthy.
+func (u *upstreams) healthy(up *upstream) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return up.healthy
+}
+
+// observe records the outcome of a request to up: a success marks it healthy
+// and updates its latency, UnhealthyAfter consecutive failures mark it
+// unhealthy.
+func (u *upstreams) observe(up *upstream, latency time.Duration, ok bool) {
+	if up == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if ok {
+		up.failures = 0
+		u.setLatency(up, latency)
+		u.setHealthy(up, true, "request succeeded")
+		return
+	}
+	up.failures++
+	if up.failures >= u.unhealthyAfter {
+		u.setHealthy(up, false, "consecutive request failures")
+	}
+}
+
+func (u *upstreams) probed(up *upstream, latency time.Duration, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	up.checked = time.Now()
+	if err != nil {
+		u.setHealthy(up, false, "probe failed: "+err.Error())
+		return
+	}
+	up.failures = 0
+	u.setLatency(up, latency)
+	u.setHealthy(up, true, "probe succeeded")
+}
+
+func (u *upstreams) setLatency(up *upstream, d time.Duration) {
+	if up.latency == 0 {
+		up.latency = d
+	} else {
+		up.latency = (7*up.latency + 3*d) / 10
+	}
+	upstreamLatency.Set(float64(up.latency.Milliseconds()), up.name)
+}
+
+func (u *upstreams) setHealthy(up *upstream, healthy bool, why string) {
+	if up.healthy == healthy {
+		return
+	}
+	up.healthy = healthy
+	if healthy {
+		logx.Infof("injective upstream %s healthy again: %s", up.name, why)
+		upstreamHealthy.Set(1, up.name)
+	} else {
+		logx.Errorf("injective upstream %s unhealthy: %s", up.name, why)
+		upstreamHealthy.Set(0, up.name)
+	}
+}
+
+func (u *upstreams) status() []UpstreamStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := make([]UpstreamStatus, 0, len(u.list))
+	for _, up := range u.list {
+		out = append(out, UpstreamStatus{
+			URL:       up.name,
+			Healthy:   up.healthy,
+			LatencyMs: up.latency.Milliseconds(),
+			Failures:  up.failures,
+			CheckedAt: up.checked,
+		})
+	}
+	return out
+}
+
+// target returns req addressed to up: the API path of req below the base URL
+// of up, with the same query.
+func (up *upstream) target(req *http.Request) *http.Request {
+	if up == nil {
+		return req
+	}
+	path := req.URL.Path
+	if i := strings.Index(path, apiPrefix); i >= 0 {
+		path = path[i:]
+	}
+	u := *req.URL
+	u.Scheme, u.Host, u.User = up.base.Scheme, up.base.Host, up.base.User
+	u.Path, u.RawPath = strings.TrimRight(up.base.Path, "/")+path, ""
+	out := req.Clone(req.Context())
+	out.URL, out.Host = &u, u.Host
+	return out
+}
+
+// Upstreams returns the health of every configured upstream, primary first.
+func (c *Client) Upstreams() []UpstreamStatus {
+	return c.upstreams.status()
+}
+
+// Probe checks every upstream every Probe.IntervalSec until ctx is done. It
+// returns at once with fewer than two upstreams: there is nothing to choose.
+func (c *Client) Probe(ctx context.Context) {
+	if len(c.upstreams.list) < 2 {
+		return
+	}
+	interval := time.Duration(max(c.cfg.Probe.IntervalSec, 1)) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.probeAll(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) probeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, up := range c.upstreams.list {
+		wg.Add(1)
+		go func(up *upstream) {
+			defer wg.Done()
+			latency, err := c.probe(ctx, up)
+			if ctx.Err() == nil {
+				c.upstreams.probed(up, latency, err)
+			}
+		}(up)
+	}
+	wg.Wait()
+}
+
+// probe sends the probe request to up directly, outside the request budget.
+func (c *Client) probe(ctx context.Context, up *upstream) (time.Duration, error) {
+	timeout := time.Duration(c.cfg.Probe.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, up.name+c.cfg.Probe.Path, nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("http %d", resp.StatusCode)
+	}
+	return time.Since(start), nil
+}
@@ -0,0 +1,84 @@
+package injective
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+)
+
+func TestClientFailsOverToHealthyUpstream(t *testing.T) {
+	var downCalls, upCalls atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downCalls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mirror"+consts.SpotConfigPath {
+			t.Errorf("path = %s, want /mirror%s", r.URL.Path, consts.SpotConfigPath)
+		}
+		upCalls.Add(1)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer up.Close()
+
+	cfg := config.InjectiveConf{BaseURL: down.URL, BaseURLs: []string{up.URL + "/mirror/"}}
+	cfg.Probe.UnhealthyAfter = 1
+	c := NewClient(cfg, http.DefaultClient)
+	for i := 0; i < 2; i++ {
+		if _, err := c.SpotConfig(context.Background()); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	// 第一次失败后主上游被标记为不健康，第二次直接发往镜像
+	if d, u := downCalls.Load(), upCalls.Load(); d != 1 || u != 2 {
+		t.Fatalf("calls down=%d up=%d, want 1 and 2", d, u)
+	}
+	st := c.Upstreams()
+	if len(st) != 2 || st[0].Healthy || !st[1].Healthy {
+		t.Fatalf("unexpected upstream status: %+v", st)
+	}
+}
+
+func TestUpstreamsPickPrefersHealthyThenLatency(t *testing.T) {
+	u := newUpstreams(config.InjectiveConf{BaseURLs: []string{"http://a", "http://b", "http://c"}})
+	a, b, c := u.list[0], u.list[1], u.list[2]
+	u.probed(a, 0, context.DeadlineExceeded)
+	u.probed(b, 80*time.Millisecond, nil)
+	u.probed(c, 20*time.Millisecond, nil)
+
+	if got := u.pick(nil); got != c {
+		t.Fatalf("pick = %s, want http://c", got.name)
+	}
+	if got := u.pick(map[*upstream]bool{c: true}); got != b {
+		t.Fatalf("pick = %s, want http://b", got.name)
+	}
+	if got := u.pick(map[*upstream]bool{b: true, c: true}); got != a {
+		t.Fatalf("pick = %s, want the unhealthy http://a last", got.name)
+	}
+	if got := u.pick(map[*upstream]bool{a: true, b: true, c: true}); got != nil {
+		t.Fatalf("pick = %s, want nil once all were tried", got.name)
+	}
+}
+
+func TestClientProbeMarksUpstreams(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+
+	c := NewClient(config.InjectiveConf{BaseURLs: []string{bad.URL, ok.URL}}, http.DefaultClient)
+	c.probeAll(context.Background())
+	st := c.Upstreams()
+	if st[0].Healthy || !st[1].Healthy || st[1].CheckedAt.IsZero() {
+		t.Fatalf("unexpected upstream status: %+v", st)
+	}
+}
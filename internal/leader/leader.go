@@ -0,0 +1,180 @@
+// Package leader elects one process among the replicas through a Redis key,
+// so work that must not run twice (the cron scheduler) runs on the leader only.
+package leader
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 仅当 key 仍属于自己（value == id）时才续期 / 删除
+var (
+	extendScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+    return redis.call('pexpire', KEYS[1], ARGV[2])
+else
+    return 0
+end`)
+	resignScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+    return redis.call('del', KEYS[1])
+else
+    return 0
+end`)
+)
+
+// Elector campaigns for the leader key. The key holds the id of the leader
+// and expires after ttl unless the leader renews it, so a crashed leader is
+// replaced within ttl; a leader that stops cleanly resigns at once.
+type Elector struct {
+	rdb *redis.Client
+	key string
+	id  string
+	ttl time.Duration
+
+	mu     sync.RWMutex
+	leader bool
+	since  time.Time
+}
+
+// New returns an elector for key; ttl defaults to 10s.
+func New(rdb *redis.Client, key string, ttl time.Duration) *Elector {
+	if ttl <= 0 {
+		ttl = 10 * time.Second
+	}
+	return &Elector{rdb: rdb, key: key, id: newID(), ttl: ttl}
+}
+
+// ID identifies this process: host, pid and a random suffix.
+func (e *Elector) ID() string { return e.id }
+
+// IsLeader reports whether this process currently holds the leader key.
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Since returns when this process became leader; zero if it is not.
+func (e *Elector) Since() time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.since
+}
+
+// Leader returns the id of the current leader and the remaining lease; an
+// empty id means there is no leader right now.
+func (e *Elector) Leader(ctx context.Context) (string, time.Duration, error) {
+	id, err := e.rdb.Get(ctx, e.key).Result()
+	if err == redis.Nil {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	ttl, err := e.rdb.PTTL(ctx, e.key).Result()
+	return id, ttl, err
+}
+
+// Run campaigns until ctx is done. Each time this process is elected, lead
+// runs with a context that is cancelled when leadership is lost; Run waits for
+// lead to return before campaigning again, so two leaders never overlap in
+// this process. On return Run resigns, letting another replica take over
+// immediately.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	retry := e.ttl / 3
+	for {
+		ok, err := e.rdb.SetNX(ctx, e.key, e.id, e.ttl).Result()
+		if err != nil && ctx.Err() == nil {
+			logx.Errorf("leader: campaign %s: %v", e.key, err)
+		}
+		if ok {
+			e.lead(ctx, lead)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// lead runs fn while renewing the lease every ttl/3.
+func (e *Elector) lead(ctx context.Context, fn func(ctx context.Context)) {
+	logx.Infof("leader: %s elected for %s", e.id, e.key)
+	e.setLeader(true)
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(leadCtx)
+	}()
+
+	ticker := time.NewTicker(e.ttl / 3)
+	renewed := time.Now()
+loop:
+	for {
+		select {
+		case <-leadCtx.Done():
+			break loop
+		case <-done:
+			break loop
+		case <-ticker.C:
+		}
+		rctx, rcancel := context.WithTimeout(context.Background(), e.ttl/3)
+		n, err := extendScript.Run(rctx, e.rdb, []string{e.key}, e.id, e.ttl.Milliseconds()).Int()
+		rcancel()
+		switch {
+		case err == nil && n == 1:
+			renewed = time.Now()
+		case err == nil:
+			logx.Errorf("leader: %s lost %s to another replica", e.id, e.key)
+			break loop
+		case time.Since(renewed) >= e.ttl:
+			logx.Errorf("leader: %s could not renew %s before it expired: %v", e.id, e.key, err)
+			break loop
+		default:
+			logx.Errorf("leader: renew %s: %v", e.key, err)
+		}
+	}
+	ticker.Stop()
+	cancel()
+	<-done
+	e.setLeader(false)
+	e.resign()
+	logx.Infof("leader: %s stepped down for %s", e.id, e.key)
+}
+
+func (e *Elector) setLeader(v bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leader = v
+	e.since = time.Time{}
+	if v {
+		e.since = time.Now()
+	}
+}
+
+// resign deletes the leader key if this process still holds it.
+func (e *Elector) resign() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := resignScript.Run(ctx, e.rdb, []string{e.key}, e.id).Err(); err != nil {
+		logx.Errorf("leader: resign %s: %v", e.key, err)
+	}
+}
+
+func newID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = crand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
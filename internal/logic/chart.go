@@ -3,10 +3,15 @@ package logic
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/zeromicro/go-zero/core/logx"
 
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
 	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
 	"github.com/biya-coin/injective-chronos-go/internal/svc"
 )
 
@@ -20,13 +25,18 @@ func NewChartLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ChartLogic
 	return &ChartLogic{Logger: logx.WithContext(ctx), ctx: ctx, svcCtx: svcCtx}
 }
 
-func (l *ChartLogic) GetMarketSummaryAll(ctx context.Context, marketType consts.MarketType, resolution string) (interface{}, error) {
-
+// GetMarketSummaryAll returns the summary_all list of a resolution. A positive at
+// (unix seconds) in the past selects the snapshot that was current at that time;
+// otherwise the newest snapshot is returned.
+func (l *ChartLogic) GetMarketSummaryAll(ctx context.Context, marketType consts.MarketType, resolution string, at int64) (interface{}, error) {
+	if at >= time.Now().Unix() {
+		at = 0
+	}
 	if marketType == consts.MarketTypeDerivative {
-		return l.getMarketSummaryAllDerivative(ctx, resolution)
+		return l.getMarketSummaryAllDerivative(ctx, resolution, at)
 	}
 	if marketType == consts.MarketTypeSpot {
-		return l.getMarketSummaryAllSpot(ctx, resolution)
+		return l.getMarketSummaryAllSpot(ctx, resolution, at)
 	}
 	return nil, errors.New("invalid market type")
 }
@@ -43,3 +53,79 @@ func (l *ChartLogic) GetMarketSummary(ctx context.Context, marketType consts.Mar
 	}
 	return nil, errors.New("invalid market type")
 }
+
+// historyCacheTTL 返回 K 线缓存的 TTL：半根 bar 的时长，且不超过默认 TTL。最新的 bar
+// 会被定时任务持续覆盖，缓存过久会把未收盘的旧值返回给前端。无法识别的 resolution 使用默认 TTL。
+func (l *ChartLogic) historyCacheTTL(resolution string) int {
+	base := l.svcCtx.Config.Redis.TTLSeconds
+	seconds := candle.Seconds(resolution)
+	if seconds <= 0 {
+		return base
+	}
+	ttl := int(seconds / 2)
+	if base > 0 && ttl > base {
+		ttl = base
+	}
+	if ttl < 1 {
+		ttl = 1
+	}
+	return ttl
+}
+
+// ErrUnsupportedResolution is returned for a history resolution that is neither
+// stored nor can be resampled from a stored one.
+var ErrUnsupportedResolution = errors.New("unsupported resolution")
+
+// findCandles reads q from repo, newest first like CandleRepo.Find. stored lists
+// the resolutions written by the cron jobs (svcCtx.Resolutions); any other
+// resolution is resampled from the coarsest stored resolution nesting in it,
+// with q.From widened to the start of its bar and q.Limit counting output bars.
+func findCandles(ctx context.Context, repo store.CandleRepo, stored []string, q store.CandleQuery) ([]store.Candle, error) {
+	if slices.Contains(stored, q.Resolution) {
+		return repo.Find(ctx, q)
+	}
+	target, err := candle.ParseResolution(q.Resolution)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedResolution, q.Resolution)
+	}
+	source, ok := candle.Source(target, stored)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedResolution, q.Resolution)
+	}
+	sq := q
+	sq.Resolution = source
+	if q.From > 0 {
+		sq.From = target.Bucket(q.From)
+	}
+	if q.Limit > 0 {
+		span := target.Seconds()
+		if target.Unit == candle.Month {
+			span = 31 * 86400
+		}
+		// one extra output bar, since the oldest one may be cut short by the limit
+		sq.Limit = (q.Limit + 1) * int(span/candle.Seconds(source))
+	}
+	bars, err := repo.Find(ctx, sq)
+	if err != nil {
+		return nil, err
+	}
+	out := candle.Resample(bars, target, q.Resolution)
+	if sq.Limit > 0 && len(bars) == sq.Limit && len(out) > 0 {
+		out = out[1:]
+	}
+	slices.Reverse(out)
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+// summaryAllCacheKey keys summary_all caches by market type and resolution, and
+// by at for point-in-time reads. Past snapshots never change, so those entries
+// only expire to bound memory.
+func summaryAllCacheKey(marketType consts.MarketType, resolution string, at int64) string {
+	if at > 0 {
+		return fmt.Sprintf("chart:summary_all:%s:%s:at:%d", marketType, resolution, at)
+	}
+	return fmt.Sprintf("chart:summary_all:%s:%s", marketType, resolution)
+}
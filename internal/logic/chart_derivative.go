@@ -6,17 +6,21 @@ import (
 	"fmt"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
-
 	"github.com/biya-coin/injective-chronos-go/internal/cache"
 	"github.com/biya-coin/injective-chronos-go/internal/consts"
 	"github.com/biya-coin/injective-chronos-go/internal/model"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
 )
 
-// getMarketSummaryAllDerivative returns the latest derivative summary_all, with Redis caching.
-func (l *ChartLogic) getMarketSummaryAllDerivative(ctx context.Context, resolution string) ([]model.DerivativeMarketSummary, error) {
-	cacheKey := fmt.Sprintf("chart:summary_all:%s", consts.MarketTypeDerivative)
+// getMarketSummaryAllDerivative returns the derivative summary_all current at `at` (0 = latest), with Redis caching.
+func (l *ChartLogic) getMarketSummaryAllDerivative(ctx context.Context, resolution string, at int64) ([]model.DerivativeMarketSummary, error) {
+	cacheKey := summaryAllCacheKey(consts.MarketTypeDerivative, resolution, at)
+	load := func(ctx context.Context) ([]model.DerivativeMarketSummary, error) {
+		if at > 0 {
+			return l.svcCtx.Store.DerivativeSnapshots.At(ctx, resolution, time.Unix(at, 0))
+		}
+		return l.svcCtx.Store.DerivativeSnapshots.Latest(ctx, resolution)
+	}
 	if bytes, err := cache.GetOrLoadBytes(
 		ctx,
 		l.svcCtx.Redis,
@@ -27,12 +31,11 @@ func (l *ChartLogic) getMarketSummaryAllDerivative(ctx context.Context, resoluti
 		l.svcCtx.Config.Redis.RetryMs,
 		l.svcCtx.Config.Redis.RetryMax,
 		func(ctx context.Context) ([]byte, error) {
-			opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-			var doc bson.M
-			if err := l.svcCtx.DerivativeColl.FindOne(ctx, bson.M{"kind": "summary_all", "resolution": resolution}, opts).Decode(&doc); err != nil {
+			rows, err := load(ctx)
+			if err != nil {
 				return nil, err
 			}
-			return json.Marshal(doc["data"])
+			return json.Marshal(rows)
 		},
 	); err == nil && bytes != nil {
 		var v []model.DerivativeMarketSummary
@@ -40,19 +43,13 @@ func (l *ChartLogic) getMarketSummaryAllDerivative(ctx context.Context, resoluti
 			return v, nil
 		}
 	}
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc bson.M
-	if err := l.svcCtx.DerivativeColl.FindOne(ctx, bson.M{"kind": "summary_all", "resolution": resolution}, opts).Decode(&doc); err != nil {
-		return nil, err
-	}
-	bytes, _ := json.Marshal(doc["data"])
-	_ = l.svcCtx.Redis.Set(ctx, cacheKey, bytes, 5*time.Minute).Err()
-	var v []model.DerivativeMarketSummary
-	err := json.Unmarshal(bytes, &v)
+	rows, err := load(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return v, nil
+	bytes, _ := json.Marshal(rows)
+	_ = l.svcCtx.Redis.Set(ctx, cacheKey, bytes, 5*time.Minute).Err()
+	return rows, nil
 }
 
 // getMarketSummaryDerivative returns a single derivative market summary using resolution.
@@ -68,13 +65,11 @@ func (l *ChartLogic) getMarketSummaryDerivative(ctx context.Context, market stri
 		l.svcCtx.Config.Redis.RetryMs,
 		l.svcCtx.Config.Redis.RetryMax,
 		func(ctx context.Context) ([]byte, error) {
-			opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-			var doc bson.M
-			filter := bson.M{"kind": "summary", "market": market, "resolution": resolution}
-			if err := l.svcCtx.DerivativeColl.FindOne(ctx, filter, opts).Decode(&doc); err != nil {
+			row, err := l.svcCtx.Store.DerivativeSummaries.Latest(ctx, market, resolution)
+			if err != nil {
 				return nil, err
 			}
-			return json.Marshal(doc["data"])
+			return json.Marshal(row)
 		},
 	); err == nil && bytes != nil {
 		var v model.DerivativeMarketSummary
@@ -84,30 +79,17 @@ func (l *ChartLogic) getMarketSummaryDerivative(ctx context.Context, market stri
 		}
 		return &v, nil
 	}
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc bson.M
-	filter := bson.M{"kind": "summary", "market": market, "resolution": resolution}
-	if err := l.svcCtx.DerivativeColl.FindOne(ctx, filter, opts).Decode(&doc); err == nil {
-		bytes, _ := json.Marshal(doc["data"]) // assume data field holds payload
+	if row, err := l.svcCtx.Store.DerivativeSummaries.Latest(ctx, market, resolution); err == nil {
+		bytes, _ := json.Marshal(row)
 		_ = l.svcCtx.Redis.Set(ctx, cacheKey, bytes, 5*time.Minute).Err()
-		var v model.DerivativeMarketSummary
-		err := json.Unmarshal(bytes, &v)
-		if err != nil {
-			return nil, err
-		}
-		return &v, nil
+		return row, nil
 	}
 	// not found; keep nil
 	return nil, nil
 }
 
 func (l *ChartLogic) getDerivativeConfigFromDB(ctx context.Context) (*model.ChartDerivativeConfig, error) {
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc model.ChartDerivativeConfigRawDoc
-	if err := l.svcCtx.DerivativeColl.FindOne(ctx, bson.M{"kind": "config"}, opts).Decode(&doc); err != nil {
-		return nil, err
-	}
-	return &doc.Data, nil
+	return l.svcCtx.Store.DerivativeConfigs.Latest(ctx)
 }
 
 // GetDerivativeConfig returns derivative TradingView-style config from Injective with Redis caching.
@@ -144,18 +126,14 @@ func (l *ChartLogic) GetDerivativeConfig(ctx context.Context) (*model.ChartDeriv
 }
 
 func (l *ChartLogic) getDerivativeSymbolInfoFromDB(ctx context.Context, group string) (*model.DerivativeSymbolInfo, error) {
-	cur, err := l.svcCtx.DerivativeColl.Find(ctx, bson.M{"kind": "symbol_info", "group": group})
+	doc, err := l.svcCtx.Store.DerivativeSymbols.Infos(ctx, group)
 	if err != nil {
 		return nil, err
 	}
-	var doc []model.DerivativeSymbolInfoRawDoc
-	if err := cur.All(ctx, &doc); err != nil {
-		return nil, err
-	}
 	if len(doc) == 0 {
 		return nil, fmt.Errorf("no symbol info found")
 	}
-	IntradayMultipliers := doc[0].Data.IntradayMultipliers
+	IntradayMultipliers := doc[0].IntradayMultipliers
 	var out model.DerivativeSymbolInfo = model.DerivativeSymbolInfo{
 		Symbol:              make([]string, 0),
 		Name:                make([]string, 0),
@@ -175,21 +153,21 @@ func (l *ChartLogic) getDerivativeSymbolInfoFromDB(ctx context.Context, group st
 		BarFillgaps:         make([]bool, 0),
 	}
 	for _, d := range doc {
-		out.Symbol = append(out.Symbol, d.Data.Symbol)
-		out.Name = append(out.Name, d.Data.Name)
-		out.Description = append(out.Description, d.Data.Description)
-		out.Currency = append(out.Currency, d.Data.Currency)
-		out.ExchangeListed = append(out.ExchangeListed, d.Data.ExchangeListed)
-		out.ExchangeTraded = append(out.ExchangeTraded, d.Data.ExchangeTraded)
-		out.Minmovement = append(out.Minmovement, d.Data.Minmovement)
-		out.Pricescale = append(out.Pricescale, d.Data.Pricescale)
-		out.Timezone = append(out.Timezone, d.Data.Timezone)
-		out.Type = append(out.Type, d.Data.Type)
-		out.SessionRegular = append(out.SessionRegular, d.Data.SessionRegular)
-		out.BaseCurrency = append(out.BaseCurrency, d.Data.BaseCurrency)
-		out.HasIntraday = append(out.HasIntraday, d.Data.HasIntraday)
-		out.Ticker = append(out.Ticker, d.Data.Ticker)
-		out.BarFillgaps = append(out.BarFillgaps, d.Data.BarFillgaps)
+		out.Symbol = append(out.Symbol, d.Symbol)
+		out.Name = append(out.Name, d.Name)
+		out.Description = append(out.Description, d.Description)
+		out.Currency = append(out.Currency, d.Currency)
+		out.ExchangeListed = append(out.ExchangeListed, d.ExchangeListed)
+		out.ExchangeTraded = append(out.ExchangeTraded, d.ExchangeTraded)
+		out.Minmovement = append(out.Minmovement, d.Minmovement)
+		out.Pricescale = append(out.Pricescale, d.Pricescale)
+		out.Timezone = append(out.Timezone, d.Timezone)
+		out.Type = append(out.Type, d.Type)
+		out.SessionRegular = append(out.SessionRegular, d.SessionRegular)
+		out.BaseCurrency = append(out.BaseCurrency, d.BaseCurrency)
+		out.HasIntraday = append(out.HasIntraday, d.HasIntraday)
+		out.Ticker = append(out.Ticker, d.Ticker)
+		out.BarFillgaps = append(out.BarFillgaps, d.BarFillgaps)
 	}
 	return &out, nil
 }
@@ -226,12 +204,7 @@ func (l *ChartLogic) GetDerivativeSymbolInfo(ctx context.Context, group string)
 }
 
 func (l *ChartLogic) getDerivativeSymbolsFromDB(ctx context.Context, symbol string) (*model.DerivativeSymbolsRaw, error) {
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc model.DerivativeSymbolsRawDoc
-	if err := l.svcCtx.DerivativeColl.FindOne(ctx, bson.M{"kind": "symbols", "symbol": symbol}, opts).Decode(&doc); err != nil {
-		return nil, err
-	}
-	return &doc.Data, nil
+	return l.svcCtx.Store.DerivativeSymbols.Symbols(ctx, symbol)
 }
 
 func (l *ChartLogic) GetDerivativeSymbols(ctx context.Context, symbol string) (*model.DerivativeSymbolsRaw, error) {
@@ -266,18 +239,16 @@ func (l *ChartLogic) GetDerivativeSymbols(ctx context.Context, symbol string) (*
 }
 
 func (l *ChartLogic) getDerivativeHistoryFromDB(ctx context.Context, symbol string, resolution string, from int64, to int64, countback int) (*model.DerivativeHistory, error) {
-	opts := options.Find().SetSort(bson.D{{Key: "t", Value: -1}})
-	if countback > 0 {
-		opts.SetLimit(int64(countback))
-	}
-	var doc []model.DerivativeHistoryRawDoc
-	cur, err := l.svcCtx.DerivativeColl.Find(ctx, bson.M{"kind": "history", "symbol": symbol, "resolution": resolution, "t": bson.M{"$gte": from, "$lte": to}}, opts)
+	doc, err := findCandles(ctx, l.svcCtx.Store.DerivativeCandles, l.svcCtx.Resolutions.Derivative(ctx, symbol), store.CandleQuery{
+		Market:     symbol,
+		Resolution: resolution,
+		From:       from,
+		To:         to,
+		Limit:      countback,
+	})
 	if err != nil {
 		return nil, err
 	}
-	if err := cur.All(ctx, &doc); err != nil {
-		return nil, err
-	}
 	var out model.DerivativeHistory = model.DerivativeHistory{
 		C: make([]float64, 0),
 		H: make([]float64, 0),
@@ -287,12 +258,12 @@ func (l *ChartLogic) getDerivativeHistoryFromDB(ctx context.Context, symbol stri
 		V: make([]float64, 0),
 	}
 	for _, d := range doc {
-		out.C = append(out.C, d.Data.C)
-		out.H = append(out.H, d.Data.H)
-		out.L = append(out.L, d.Data.L)
-		out.O = append(out.O, d.Data.O)
-		out.T = append(out.T, d.Data.T)
-		out.V = append(out.V, d.Data.V)
+		out.C = append(out.C, d.C)
+		out.H = append(out.H, d.H)
+		out.L = append(out.L, d.L)
+		out.O = append(out.O, d.O)
+		out.T = append(out.T, d.T)
+		out.V = append(out.V, d.V)
 	}
 	return &out, nil
 }
@@ -303,8 +274,8 @@ func (l *ChartLogic) GetDerivativeHistory(ctx context.Context, symbol string, re
 		ctx,
 		l.svcCtx.Redis,
 		cacheKey,
-		l.svcCtx.Config.Redis.TTLSeconds,
-		l.svcCtx.Config.Redis.JitterSeconds,
+		l.historyCacheTTL(resolution),
+		1,
 		l.svcCtx.Config.Redis.LockTTLSeconds,
 		l.svcCtx.Config.Redis.RetryMs,
 		l.svcCtx.Config.Redis.RetryMax,
@@ -4,47 +4,34 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
-
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/biya-coin/injective-chronos-go/internal/cache"
 	"github.com/biya-coin/injective-chronos-go/internal/model"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
 )
 
 func (l *ChartLogic) getMarketHistoryByMarketIDs(ctx context.Context, marketIDs []string, resolution string, countback int) ([]model.MarketHistory, error) {
 	var result []model.MarketHistory
 	for _, mid := range marketIDs {
 		// find latest candles for mid
-		findOpts := options.Find()
-		findOpts.SetSort(bson.D{{Key: "t", Value: -1}})
-		if countback > 0 {
-			findOpts.SetLimit(int64(countback))
-		}
-		cur, err := l.svcCtx.MarketColl.Find(ctx, bson.M{
-			"kind":       "history",
-			"market":     mid,
-			"resolution": resolution,
-		}, findOpts)
+		points, err := findCandles(ctx, l.svcCtx.Store.MarketCandles, l.svcCtx.Resolutions.Market(ctx), store.CandleQuery{
+			Market:     mid,
+			Resolution: resolution,
+			Limit:      countback,
+		})
 		if err != nil {
 			return nil, err
 		}
-		var points []bson.M
-		if err := cur.All(ctx, &points); err != nil {
-			return nil, err
-		}
 		var out model.MarketHistory
 		out.MarketID = mid
 		out.Resolution = resolution
 		for _, p := range points {
-			data := p["data"].(bson.M)
-			out.T = append(out.T, data["t"].(int64))
-			out.O = append(out.O, data["o"].(float64))
-			out.H = append(out.H, data["h"].(float64))
-			out.L = append(out.L, data["l"].(float64))
-			out.C = append(out.C, data["c"].(float64))
-			out.V = append(out.V, data["v"].(float64))
+			out.T = append(out.T, p.T)
+			out.O = append(out.O, p.O)
+			out.H = append(out.H, p.H)
+			out.L = append(out.L, p.L)
+			out.C = append(out.C, p.C)
+			out.V = append(out.V, p.V)
 		}
 		result = append(result, out)
 	}
@@ -60,9 +47,7 @@ func (l *ChartLogic) GetMarketHistory(ctx context.Context, marketIDs []string, r
 	if resolution == "" {
 		resolution = "1"
 	}
-	// 把resolution转换为int除以2作为redis的baseTTLSeconds
-	resolutionInt, _ := strconv.Atoi(resolution)
-	baseTTLSeconds := resolutionInt * 60 / 2
+	baseTTLSeconds := l.historyCacheTTL(resolution)
 
 	if countback <= 0 {
 		countback = 0
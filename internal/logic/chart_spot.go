@@ -4,15 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
-
 	"github.com/biya-coin/injective-chronos-go/internal/cache"
 	"github.com/biya-coin/injective-chronos-go/internal/consts"
 	"github.com/biya-coin/injective-chronos-go/internal/model"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
@@ -29,12 +26,11 @@ func (l *ChartLogic) GetSpotConfig(ctx context.Context) (*model.ChartSpotConfig,
 		l.svcCtx.Config.Redis.RetryMs,
 		l.svcCtx.Config.Redis.RetryMax,
 		func(ctx context.Context) ([]byte, error) {
-			opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-			var doc bson.M
-			if err := l.svcCtx.SpotColl.FindOne(ctx, bson.M{"kind": "config"}, opts).Decode(&doc); err != nil {
+			cfg, err := l.svcCtx.Store.SpotConfigs.Latest(ctx)
+			if err != nil {
 				return nil, err
 			}
-			return json.Marshal(doc["data"])
+			return json.Marshal(cfg)
 		},
 	); err == nil && bytes != nil {
 		var v model.ChartSpotConfig
@@ -43,23 +39,24 @@ func (l *ChartLogic) GetSpotConfig(ctx context.Context) (*model.ChartSpotConfig,
 		}
 	}
 	// fallback（无缓存命中时直接读库）
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc bson.M
-	if err := l.svcCtx.SpotColl.FindOne(ctx, bson.M{"kind": "config"}, opts).Decode(&doc); err != nil {
+	cfg, err := l.svcCtx.Store.SpotConfigs.Latest(ctx)
+	if err != nil {
 		return nil, err
 	}
-	bytes, _ := json.Marshal(doc["data"])
+	bytes, _ := json.Marshal(cfg)
 	_ = l.svcCtx.Redis.Set(ctx, cacheKey, bytes, 5*time.Minute).Err()
-	var v model.ChartSpotConfig
-	if e := json.Unmarshal(bytes, &v); e != nil {
-		return nil, e
-	}
-	return &v, nil
+	return cfg, nil
 }
 
-// getMarketSummaryAllSpot returns the latest spot summary_all, with Redis caching.
-func (l *ChartLogic) getMarketSummaryAllSpot(ctx context.Context, resolution string) ([]model.SpotMarketSummary, error) {
-	cacheKey := fmt.Sprintf("chart:summary_all:%s", consts.MarketTypeSpot)
+// getMarketSummaryAllSpot returns the spot summary_all current at `at` (0 = latest), with Redis caching.
+func (l *ChartLogic) getMarketSummaryAllSpot(ctx context.Context, resolution string, at int64) ([]model.SpotMarketSummary, error) {
+	cacheKey := summaryAllCacheKey(consts.MarketTypeSpot, resolution, at)
+	load := func(ctx context.Context) ([]model.SpotMarketSummary, error) {
+		if at > 0 {
+			return l.svcCtx.Store.SpotSnapshots.At(ctx, resolution, time.Unix(at, 0))
+		}
+		return l.svcCtx.Store.SpotSnapshots.Latest(ctx, resolution)
+	}
 	if bytes, err := cache.GetOrLoadBytes(
 		ctx,
 		l.svcCtx.Redis,
@@ -70,12 +67,11 @@ func (l *ChartLogic) getMarketSummaryAllSpot(ctx context.Context, resolution str
 		l.svcCtx.Config.Redis.RetryMs,
 		l.svcCtx.Config.Redis.RetryMax,
 		func(ctx context.Context) ([]byte, error) {
-			opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-			var doc bson.M
-			if err := l.svcCtx.SpotColl.FindOne(ctx, bson.M{"kind": "summary_all", "resolution": resolution}, opts).Decode(&doc); err != nil {
+			rows, err := load(ctx)
+			if err != nil {
 				return nil, err
 			}
-			return json.Marshal(doc["data"])
+			return json.Marshal(rows)
 		},
 	); err == nil && bytes != nil {
 		var v []model.SpotMarketSummary
@@ -83,19 +79,13 @@ func (l *ChartLogic) getMarketSummaryAllSpot(ctx context.Context, resolution str
 			return v, nil
 		}
 	}
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc bson.M
-	if err := l.svcCtx.SpotColl.FindOne(ctx, bson.M{"kind": "summary_all", "resolution": resolution}, opts).Decode(&doc); err != nil {
-		return nil, err
-	}
-	bytes, _ := json.Marshal(doc["data"])
-	_ = l.svcCtx.Redis.Set(ctx, cacheKey, bytes, 5*time.Minute).Err()
-	var v []model.SpotMarketSummary
-	err := json.Unmarshal(bytes, &v)
+	rows, err := load(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return v, nil
+	bytes, _ := json.Marshal(rows)
+	_ = l.svcCtx.Redis.Set(ctx, cacheKey, bytes, 5*time.Minute).Err()
+	return rows, nil
 }
 
 // getMarketSummarySpot returns a single spot market summary.
@@ -111,13 +101,11 @@ func (l *ChartLogic) getMarketSummarySpot(ctx context.Context, market string, re
 		l.svcCtx.Config.Redis.RetryMs,
 		l.svcCtx.Config.Redis.RetryMax,
 		func(ctx context.Context) ([]byte, error) {
-			opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-			var doc bson.M
-			filter := bson.M{"kind": "summary", "market": market, "resolution": resolution}
-			if err := l.svcCtx.SpotColl.FindOne(ctx, filter, opts).Decode(&doc); err != nil {
+			row, err := l.svcCtx.Store.SpotSummaries.Latest(ctx, market, resolution)
+			if err != nil {
 				return nil, err
 			}
-			return json.Marshal(doc["data"])
+			return json.Marshal(row)
 		},
 	); err == nil && bytes != nil {
 		var v model.SpotMarketSummary
@@ -127,44 +115,27 @@ func (l *ChartLogic) getMarketSummarySpot(ctx context.Context, market string, re
 		}
 		return &v, nil
 	}
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc bson.M
-	filter := bson.M{"kind": "summary", "market": market, "resolution": resolution}
-	if err := l.svcCtx.SpotColl.FindOne(ctx, filter, opts).Decode(&doc); err == nil {
-		bytes, _ := json.Marshal(doc["data"]) // assume data field holds payload
+	if row, err := l.svcCtx.Store.SpotSummaries.Latest(ctx, market, resolution); err == nil {
+		bytes, _ := json.Marshal(row)
 		_ = l.svcCtx.Redis.Set(ctx, cacheKey, bytes, 5*time.Minute).Err()
-		var v model.SpotMarketSummary
-		err := json.Unmarshal(bytes, &v)
-		if err != nil {
-			return nil, err
-		}
-		return &v, nil
+		return row, nil
 	}
 	// not found; keep nil
 	return nil, nil
 }
 
 func (l *ChartLogic) getMarketHistorySpotByMarketIDs(ctx context.Context, marketId string, resolution string, countback int, from int64, to int64) (model.SpotMarketHistory, error) {
-	findOpts := options.Find()
-	findOpts.SetSort(bson.D{{Key: "t", Value: -1}})
-	if countback > 0 {
-		findOpts.SetLimit(int64(countback))
-	}
-	cur, err := l.svcCtx.SpotColl.Find(ctx, bson.M{
-		"kind":       "history",
-		"market":     marketId,
-		"resolution": resolution,
-		"t":          bson.M{"$gte": from, "$lte": to},
-	}, findOpts)
+	points, err := findCandles(ctx, l.svcCtx.Store.SpotCandles, l.svcCtx.Resolutions.Market(ctx), store.CandleQuery{
+		Market:     marketId,
+		Resolution: resolution,
+		From:       from,
+		To:         to,
+		Limit:      countback,
+	})
 	if err != nil {
 		logx.Errorf("getMarketHistorySpotByMarketIDs find error: %v", err)
 		return model.SpotMarketHistory{}, err
 	}
-	var points []model.SpotHistoryDoc
-	if err := cur.All(ctx, &points); err != nil {
-		logx.Errorf("getMarketHistorySpotByMarketIDs all error: %v", err)
-		return model.SpotMarketHistory{}, err
-	}
 	var out model.SpotMarketHistory = model.SpotMarketHistory{
 		T: make([]int64, 0),
 		O: make([]float64, 0),
@@ -175,14 +146,12 @@ func (l *ChartLogic) getMarketHistorySpotByMarketIDs(ctx context.Context, market
 	}
 	logx.Debugf("getMarketHistorySpotByMarketIDs----------------> points: %+v", points)
 	for _, p := range points {
-		data := p.Data
-		// 使用bson unmarshal
-		out.T = append(out.T, data.T)
-		out.O = append(out.O, data.O)
-		out.H = append(out.H, data.H)
-		out.L = append(out.L, data.L)
-		out.C = append(out.C, data.C)
-		out.V = append(out.V, data.V)
+		out.T = append(out.T, p.T)
+		out.O = append(out.O, p.O)
+		out.H = append(out.H, p.H)
+		out.L = append(out.L, p.L)
+		out.C = append(out.C, p.C)
+		out.V = append(out.V, p.V)
 	}
 	return out, nil
 }
@@ -195,9 +164,7 @@ func (l *ChartLogic) GetMarketHistorySpot(ctx context.Context, marketId string,
 	if resolution == "" {
 		resolution = "1"
 	}
-	// 把resolution转换为int除以2作为redis的baseTTLSeconds
-	resolutionInt, _ := strconv.Atoi(resolution)
-	baseTTLSeconds := resolutionInt * 60 / 2
+	baseTTLSeconds := l.historyCacheTTL(resolution)
 
 	if countback <= 0 {
 		countback = 0
@@ -236,18 +203,14 @@ func (l *ChartLogic) GetMarketHistorySpot(ctx context.Context, marketId string,
 }
 
 func (l *ChartLogic) getSpotSymbolInfoFromDB(ctx context.Context, group string) (*model.SpotSymbolInfo, error) {
-	cur, err := l.svcCtx.SpotColl.Find(ctx, bson.M{"kind": "symbol_info", "group": group})
+	doc, err := l.svcCtx.Store.SpotSymbols.Infos(ctx, group)
 	if err != nil {
 		return nil, err
 	}
-	var doc []model.SpotSymbolInfoRawDoc
-	if err := cur.All(ctx, &doc); err != nil {
-		return nil, err
-	}
 	if len(doc) == 0 {
 		return nil, fmt.Errorf("no symbol info found")
 	}
-	IntradayMultipliers := doc[0].Data.IntradayMultipliers
+	IntradayMultipliers := doc[0].IntradayMultipliers
 	var out model.SpotSymbolInfo = model.SpotSymbolInfo{
 		Symbol:              make([]string, 0),
 		Name:                make([]string, 0),
@@ -267,21 +230,21 @@ func (l *ChartLogic) getSpotSymbolInfoFromDB(ctx context.Context, group string)
 		BarFillgaps:         make([]bool, 0),
 	}
 	for _, d := range doc {
-		out.Symbol = append(out.Symbol, d.Data.Symbol)
-		out.Name = append(out.Name, d.Data.Name)
-		out.Description = append(out.Description, d.Data.Description)
-		out.Currency = append(out.Currency, d.Data.Currency)
-		out.ExchangeListed = append(out.ExchangeListed, d.Data.ExchangeListed)
-		out.ExchangeTraded = append(out.ExchangeTraded, d.Data.ExchangeTraded)
-		out.Minmovement = append(out.Minmovement, d.Data.Minmovement)
-		out.Pricescale = append(out.Pricescale, d.Data.Pricescale)
-		out.Timezone = append(out.Timezone, d.Data.Timezone)
-		out.Type = append(out.Type, d.Data.Type)
-		out.SessionRegular = append(out.SessionRegular, d.Data.SessionRegular)
-		out.BaseCurrency = append(out.BaseCurrency, d.Data.BaseCurrency)
-		out.HasIntraday = append(out.HasIntraday, d.Data.HasIntraday)
-		out.Ticker = append(out.Ticker, d.Data.Ticker)
-		out.BarFillgaps = append(out.BarFillgaps, d.Data.BarFillgaps)
+		out.Symbol = append(out.Symbol, d.Symbol)
+		out.Name = append(out.Name, d.Name)
+		out.Description = append(out.Description, d.Description)
+		out.Currency = append(out.Currency, d.Currency)
+		out.ExchangeListed = append(out.ExchangeListed, d.ExchangeListed)
+		out.ExchangeTraded = append(out.ExchangeTraded, d.ExchangeTraded)
+		out.Minmovement = append(out.Minmovement, d.Minmovement)
+		out.Pricescale = append(out.Pricescale, d.Pricescale)
+		out.Timezone = append(out.Timezone, d.Timezone)
+		out.Type = append(out.Type, d.Type)
+		out.SessionRegular = append(out.SessionRegular, d.SessionRegular)
+		out.BaseCurrency = append(out.BaseCurrency, d.BaseCurrency)
+		out.HasIntraday = append(out.HasIntraday, d.HasIntraday)
+		out.Ticker = append(out.Ticker, d.Ticker)
+		out.BarFillgaps = append(out.BarFillgaps, d.BarFillgaps)
 	}
 	return &out, nil
 }
@@ -320,12 +283,7 @@ func (l *ChartLogic) GetSpotSymbolInfo(ctx context.Context, group string) (*mode
 }
 
 func (l *ChartLogic) getSpotSymbolsFromDB(ctx context.Context, symbol string) (*model.SpotSymbolsRaw, error) {
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc model.SpotSymbolsRawDoc
-	if err := l.svcCtx.SpotColl.FindOne(ctx, bson.M{"kind": "symbols", "symbol": symbol}, opts).Decode(&doc); err != nil {
-		return nil, err
-	}
-	return &doc.Data, nil
+	return l.svcCtx.Store.SpotSymbols.Symbols(ctx, symbol)
 }
 
 func (l *ChartLogic) GetSpotSymbols(ctx context.Context, symbol string) (*model.SpotSymbolsRaw, error) {
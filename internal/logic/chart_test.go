@@ -0,0 +1,45 @@
+package logic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+func TestFindCandlesResamplesUnstoredResolution(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryStore().SpotCandles
+	// daily bars Mon 2024-05-06 .. Wed 2024-05-15
+	start := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC).Unix()
+	var bars []store.Candle
+	for i := int64(0); i < 10; i++ {
+		bars = append(bars, store.Candle{Market: "m", Resolution: "1440", T: start + i*86400, O: float64(i), H: float64(i) + 1, L: float64(i), C: float64(i) + 0.5, V: 1})
+	}
+	_, _ = repo.Upsert(ctx, bars)
+
+	got, err := findCandles(ctx, repo, consts.SupportedMarketResolutions, store.CandleQuery{Market: "m", Resolution: "1W"})
+	if err != nil {
+		t.Fatalf("findCandles: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d weekly bars, want 2: %#v", len(got), got)
+	}
+	week2, week1 := got[0], got[1]
+	if week1.T != start || week1.O != 0 || week1.C != 6.5 || week1.H != 7 || week1.V != 7 {
+		t.Fatalf("unexpected first week: %#v", week1)
+	}
+	if week2.T != start+7*86400 || week2.V != 3 || week2.Resolution != "1W" {
+		t.Fatalf("unexpected second week: %#v", week2)
+	}
+
+	got, _ = findCandles(ctx, repo, consts.SupportedMarketResolutions, store.CandleQuery{Market: "m", Resolution: "1W", Limit: 1})
+	if len(got) != 1 || got[0].T != start+7*86400 {
+		t.Fatalf("limit must keep the newest bar: %#v", got)
+	}
+	if _, err := findCandles(ctx, repo, consts.SupportedMarketResolutions, store.CandleQuery{Market: "m", Resolution: "1S"}); err == nil {
+		t.Fatalf("unsupported resolution must fail")
+	}
+}
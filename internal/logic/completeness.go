@@ -0,0 +1,83 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+// SeriesCompleteness reports how many bars of one stored series are missing
+// between its oldest and newest bar.
+type SeriesCompleteness struct {
+	Market       string       `json:"market"`
+	Resolution   string       `json:"resolution"`
+	First        int64        `json:"first"`
+	Last         int64        `json:"last"`
+	Bars         int64        `json:"bars"`
+	Expected     int64        `json:"expected"`
+	Missing      int64        `json:"missing"`
+	Completeness float64      `json:"completeness"`
+	Gaps         []candle.Gap `json:"gaps,omitempty"`
+}
+
+// GetCandleCompleteness reports every series of a history collection
+// (spot, derivative or market). Gap windows are listed only when market is
+// given, since that requires reading every bar time of the series. A positive
+// since limits the report to the bars with t >= since.
+func (l *ChartLogic) GetCandleCompleteness(ctx context.Context, marketType consts.MarketType, market string, since int64) ([]SeriesCompleteness, error) {
+	var repo store.CandleRepo
+	switch marketType {
+	case consts.MarketTypeSpot:
+		repo = l.svcCtx.Store.SpotCandles
+	case consts.MarketTypeDerivative:
+		repo = l.svcCtx.Store.DerivativeCandles
+	case consts.MarketTypeMarket:
+		repo = l.svcCtx.Store.MarketCandles
+	default:
+		return nil, fmt.Errorf("invalid market type %q", marketType)
+	}
+	if repo == nil {
+		return nil, fmt.Errorf("no %s history collection configured", marketType)
+	}
+	series, err := repo.Series(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SeriesCompleteness, 0, len(series))
+	for _, s := range series {
+		if market != "" && s.Market != market {
+			continue
+		}
+		width := candle.Seconds(s.Resolution)
+		c := SeriesCompleteness{
+			Market:     s.Market,
+			Resolution: s.Resolution,
+			First:      s.First,
+			Last:       s.Last,
+			Bars:       s.Count,
+			Expected:   candle.Expected(s.First, s.Last, width),
+		}
+		if c.Expected > 0 {
+			c.Missing = max(c.Expected-c.Bars, 0)
+			c.Completeness = float64(c.Expected-c.Missing) / float64(c.Expected)
+		}
+		if market != "" && c.Missing > 0 {
+			times, err := repo.Times(ctx, s.Market, s.Resolution, since, 0)
+			if err != nil {
+				return nil, err
+			}
+			c.Gaps = candle.Gaps(times, width)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// GetSyncStates lists the ingestion watermarks of a market type; an empty
+// type lists all of them.
+func (l *ChartLogic) GetSyncStates(ctx context.Context, marketType consts.MarketType) ([]store.SyncState, error) {
+	return l.svcCtx.Store.SyncStates.List(ctx, string(marketType))
+}
@@ -0,0 +1,105 @@
+package logic
+
+import (
+	"context"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+// Job statuses, derived from the last run of a job.
+const (
+	JobStatusOK      = "ok"
+	JobStatusFailing = "failing" // the last run had failures
+	JobStatusSkipped = "skipped" // the last run found the job locked by another replica
+	JobStatusPaused  = "paused"  // paused through the admin API or CLI
+)
+
+// JobStatus is the latest state of one cron job.
+type JobStatus struct {
+	store.JobSummary
+	Paused bool   `json:"paused"`
+	Status string `json:"status"`
+}
+
+// GetJobs lists every cron job that has recorded runs or is paused, failing
+// ones first.
+func (l *ChartLogic) GetJobs(ctx context.Context) ([]JobStatus, error) {
+	jobs, err := l.svcCtx.Store.JobRuns.Jobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	states, err := l.svcCtx.Store.JobStates.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paused := map[string]bool{}
+	for _, s := range states {
+		if s.Paused {
+			paused[s.Job] = true
+		}
+	}
+	out := make([]JobStatus, 0, len(jobs))
+	var failing []JobStatus
+	for _, j := range jobs {
+		s := JobStatus{JobSummary: j, Paused: paused[j.Job], Status: JobStatusOK}
+		delete(paused, j.Job)
+		switch {
+		case s.Paused:
+			s.Status = JobStatusPaused
+		case j.Last.Skipped:
+			s.Status = JobStatusSkipped
+		case !j.Last.OK():
+			s.Status = JobStatusFailing
+			failing = append(failing, s)
+			continue
+		}
+		out = append(out, s)
+	}
+	for _, st := range states {
+		if paused[st.Job] {
+			out = append(out, JobStatus{JobSummary: store.JobSummary{Job: st.Job}, Paused: true, Status: JobStatusPaused})
+		}
+	}
+	return append(failing, out...), nil
+}
+
+// GetJobRuns returns the newest runs of a job, newest first. limit defaults
+// to 50 and is capped at 500.
+func (l *ChartLogic) GetJobRuns(ctx context.Context, job string, limit int) ([]store.JobRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return l.svcCtx.Store.JobRuns.Runs(ctx, job, min(limit, 500))
+}
+
+// LeaderStatus reports which replica runs the cron scheduler.
+type LeaderStatus struct {
+	Enabled  bool   `json:"enabled"`
+	Instance string `json:"instance"` // id of the replica that answered
+	IsLeader bool   `json:"isLeader"`
+	// Leader is the id of the current leader; empty while the lease is vacant.
+	Leader  string    `json:"leader"`
+	LeaseMs int64     `json:"leaseMs"` // remaining leader lease
+	Since   time.Time `json:"since"`   // when this replica became leader; zero if it is not
+}
+
+// GetLeader reports the current cron leader as seen from this replica.
+func (l *ChartLogic) GetLeader(ctx context.Context) (*LeaderStatus, error) {
+	e := l.svcCtx.Leader
+	if !l.svcCtx.Config.Cron.Leader.Enabled || e == nil {
+		return &LeaderStatus{}, nil
+	}
+	id, lease, err := e.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &LeaderStatus{
+		Enabled:  true,
+		Instance: e.ID(),
+		IsLeader: e.IsLeader(),
+		Leader:   id,
+		LeaseMs:  lease.Milliseconds(),
+		Since:    e.Since(),
+	}, nil
+}
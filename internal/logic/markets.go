@@ -0,0 +1,36 @@
+package logic
+
+import (
+	"context"
+
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+// GetMarkets lists the market registry of a market type, or of spot and
+// derivative when marketType is empty. Delisted markets are included unless
+// activeOnly is set.
+func (l *ChartLogic) GetMarkets(ctx context.Context, marketType consts.MarketType, activeOnly bool) ([]store.Market, error) {
+	types := []consts.MarketType{marketType}
+	if marketType == "" {
+		types = []consts.MarketType{consts.MarketTypeSpot, consts.MarketTypeDerivative}
+	}
+	out := []store.Market{}
+	for _, typ := range types {
+		markets, err := l.svcCtx.Store.Markets.List(ctx, string(typ), activeOnly)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, markets...)
+	}
+	return out, nil
+}
+
+// GetMarketEvents returns the newest market listing changes, newest first.
+// limit defaults to 50 and is capped at 500.
+func (l *ChartLogic) GetMarketEvents(ctx context.Context, marketType consts.MarketType, limit int) ([]store.MarketEvent, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return l.svcCtx.Store.Markets.Events(ctx, string(marketType), min(limit, 500))
+}
@@ -0,0 +1,161 @@
+// Package migrate applies versioned schema changes (indexes and document
+// rewrites) to the Spot, Derivative and Market collections.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection is where applied migrations are recorded, in the same database as
+// the data collections.
+const Collection = "schema_migrations"
+
+const (
+	StateRunning = "running"
+	StateApplied = "applied"
+)
+
+// ErrLocked is returned when another process is applying a migration.
+var ErrLocked = errors.New("migrate: migration in progress elsewhere")
+
+// Collections are the collections migrations operate on. Market may be nil
+// when no market collection is configured.
+type Collections struct {
+	Spot       *mongo.Collection
+	Derivative *mongo.Collection
+	Market     *mongo.Collection
+}
+
+// Migration is one schema change. Up must be safe to re-run, since a process
+// may die after Up succeeds but before the migration is recorded.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, c Collections) error
+}
+
+// Record is the stored state of a migration.
+type Record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	State     string    `bson:"state"`
+	StartedAt time.Time `bson:"started_at"`
+	AppliedAt time.Time `bson:"applied_at,omitempty"`
+}
+
+// Runner applies migrations in version order, each at most once.
+type Runner struct {
+	colls      Collections
+	records    *mongo.Collection
+	migrations []Migration
+	// staleAfter is how long a `running` claim is honoured before it is
+	// considered abandoned by a crashed process and taken over.
+	staleAfter time.Duration
+}
+
+// NewRunner builds a Runner for the given migrations; the records are kept in
+// Collection of the spot collection's database.
+func NewRunner(colls Collections, migrations []Migration) *Runner {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Runner{
+		colls:      colls,
+		records:    colls.Spot.Database().Collection(Collection),
+		migrations: sorted,
+		staleAfter: 30 * time.Minute,
+	}
+}
+
+// Status returns the stored record of every known migration, keyed by version.
+func (r *Runner) Status(ctx context.Context) (map[int]Record, error) {
+	cur, err := r.records.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var recs []Record
+	if err := cur.All(ctx, &recs); err != nil {
+		return nil, err
+	}
+	out := make(map[int]Record, len(recs))
+	for _, rec := range recs {
+		out[rec.Version] = rec
+	}
+	return out, nil
+}
+
+// Migrations returns the migrations known to the runner in version order.
+func (r *Runner) Migrations() []Migration {
+	return r.migrations
+}
+
+// Run applies every pending migration in order and returns the applied ones.
+// It stops at the first failure, or with ErrLocked when another process holds a
+// migration, since later migrations may depend on earlier ones.
+func (r *Runner) Run(ctx context.Context) ([]Migration, error) {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var applied []Migration
+	for _, m := range pending(r.migrations, status) {
+		ok, err := r.claim(ctx, m)
+		if err != nil {
+			return applied, err
+		}
+		if !ok {
+			return applied, fmt.Errorf("%w: %d %s", ErrLocked, m.Version, m.Name)
+		}
+		logx.Infof("migrate: applying %d %s", m.Version, m.Name)
+		if err := m.Up(ctx, r.colls); err != nil {
+			// release the claim so the next run retries
+			_, _ = r.records.DeleteOne(context.Background(), bson.M{"_id": m.Version, "state": StateRunning})
+			return applied, fmt.Errorf("migrate %d %s: %w", m.Version, m.Name, err)
+		}
+		if _, err := r.records.UpdateOne(ctx, bson.M{"_id": m.Version}, bson.M{"$set": bson.M{
+			"state":      StateApplied,
+			"applied_at": time.Now(),
+		}}); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// claim marks m as running. It succeeds when no record exists or the existing
+// claim is stale; the unique _id makes concurrent claims fail for all but one.
+func (r *Runner) claim(ctx context.Context, m Migration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id":        m.Version,
+		"state":      StateRunning,
+		"started_at": bson.M{"$lt": now.Add(-r.staleAfter)},
+	}
+	update := bson.M{"$set": bson.M{"name": m.Name, "state": StateRunning, "started_at": now}}
+	_, err := r.records.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// pending returns the migrations without an applied record, in order.
+func pending(migrations []Migration, status map[int]Record) []Migration {
+	var out []Migration
+	for _, m := range migrations {
+		if rec, ok := status[m.Version]; ok && rec.State == StateApplied {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
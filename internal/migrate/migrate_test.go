@@ -0,0 +1,28 @@
+package migrate
+
+import "testing"
+
+func TestAllVersionsAreUniqueAndAscending(t *testing.T) {
+	prev := 0
+	for _, m := range All() {
+		if m.Version <= prev {
+			t.Fatalf("migration %d %s must have a version above %d", m.Version, m.Name, prev)
+		}
+		if m.Name == "" || m.Up == nil {
+			t.Fatalf("migration %d is incomplete", m.Version)
+		}
+		prev = m.Version
+	}
+}
+
+func TestPendingSkipsOnlyApplied(t *testing.T) {
+	all := All()
+	status := map[int]Record{
+		1: {Version: 1, State: StateApplied},
+		2: {Version: 2, State: StateRunning},
+	}
+	got := pending(all, status)
+	if len(got) != len(all)-1 || got[0].Version != 2 {
+		t.Fatalf("unexpected pending migrations: %#v", got)
+	}
+}
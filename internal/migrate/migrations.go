@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+// All returns the migrations of this service. Append new ones with the next
+// version; never renumber or edit a released migration.
+func All() []Migration {
+	return []Migration{
+		{Version: 1, Name: "market_history_rename_marketId", Up: renameMarketHistoryKey},
+		{Version: 2, Name: "history_unique_indexes", Up: historyUniqueIndexes},
+		{Version: 3, Name: "snapshot_lookup_indexes", Up: snapshotLookupIndexes},
+		{Version: 4, Name: "checkpoint_key_index", Up: checkpointKeyIndex},
+		{Version: 5, Name: "job_runs_indexes", Up: jobRunsIndexes},
+		{Version: 6, Name: "market_registry_indexes", Up: marketRegistryIndexes},
+		{Version: 7, Name: "history_time_indexes", Up: historyTimeIndexes},
+	}
+}
+
+// renameMarketHistoryKey moves MarketColl history documents from `marketId` to
+// `market`, the key the Spot collection already uses. The unique index built on
+// the old field is dropped first, otherwise every renamed document would
+// collide on a null marketId.
+func renameMarketHistoryKey(ctx context.Context, c Collections) error {
+	if c.Market == nil {
+		return nil
+	}
+	if err := dropIndex(ctx, c.Market, "uniq_history_marketId_resolution_t"); err != nil {
+		return err
+	}
+	_, err := c.Market.UpdateMany(ctx,
+		bson.M{"kind": store.KindHistory, "marketId": bson.M{"$exists": true}, "market": bson.M{"$exists": false}},
+		bson.M{"$rename": bson.M{"marketId": "market"}},
+	)
+	return err
+}
+
+// historyUniqueIndexes builds the unique (kind, market, resolution, t) history
+// indexes, removing duplicate bars first when needed.
+func historyUniqueIndexes(ctx context.Context, c Collections) error {
+	return store.NewMongoStore(c.Spot, c.Derivative, c.Market).EnsureIndexes(ctx)
+}
+
+// snapshotLookupIndexes backs the "latest document" reads of the snapshot
+// kinds. Each index is partial on its kind so history documents do not bloat it.
+func snapshotLookupIndexes(ctx context.Context, c Collections) error {
+	indexes := []mongo.IndexModel{
+		kindIndex(store.KindSummaryAll, "resolution", "-updated_at"),
+		kindIndex(store.KindSummary, "market", "resolution", "-updated_at"),
+		kindIndex(store.KindConfig, "-updated_at"),
+		kindIndex(store.KindSymbolInfo, "group", "symbol"),
+		kindIndex(store.KindSymbols, "symbol", "-updated_at"),
+	}
+	for _, coll := range []*mongo.Collection{c.Spot, c.Derivative} {
+		if _, err := coll.Indexes().CreateMany(ctx, indexes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkpointKeyIndex makes checkpoint keys unique, so concurrent upserts of the
+// same key cannot create two documents.
+func checkpointKeyIndex(ctx context.Context, c Collections) error {
+	ix := kindIndex(store.KindCheckpoint, "key")
+	ix.Options.SetUnique(true)
+	_, err := c.Spot.Indexes().CreateOne(ctx, ix)
+	return err
+}
+
+// jobRunsIndexes backs the per-job run listing and expires runs after
+// store.JobRunTTL.
+func jobRunsIndexes(ctx context.Context, c Collections) error {
+	coll := c.Spot.Database().Collection(store.JobRunCollection)
+	_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "job", Value: 1}, {Key: "start", Value: -1}},
+			Options: options.Index().SetName("job_start"),
+		},
+		{
+			Keys:    bson.D{{Key: "start", Value: 1}},
+			Options: options.Index().SetName("ttl_start").SetExpireAfterSeconds(int32(store.JobRunTTL.Seconds())),
+		},
+	})
+	return err
+}
+
+// marketRegistryIndexes backs listing the registry per market type and the
+// newest market events.
+func marketRegistryIndexes(ctx context.Context, c Collections) error {
+	db := c.Spot.Database()
+	_, err := db.Collection(store.MarketCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "market_type", Value: 1}, {Key: "active", Value: 1}},
+		Options: options.Index().SetName("type_active"),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection(store.MarketEventCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "market_type", Value: 1}, {Key: "at", Value: -1}},
+		Options: options.Index().SetName("type_at"),
+	})
+	return err
+}
+
+// historyTimeIndexes backs summarizing the history series over a recent
+// window (gap repair, completeness with lookbackHours).
+func historyTimeIndexes(ctx context.Context, c Collections) error {
+	for _, coll := range []*mongo.Collection{c.Spot, c.Derivative, c.Market} {
+		if coll == nil {
+			continue
+		}
+		if _, err := coll.Indexes().CreateOne(ctx, kindIndex(store.KindHistory, "t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kindIndex builds an index on kind followed by fields; a leading "-" makes a
+// field descending. The name is derived from the kind so re-runs are no-ops.
+func kindIndex(kind string, fields ...string) mongo.IndexModel {
+	keys := bson.D{{Key: "kind", Value: 1}}
+	for _, f := range fields {
+		if f[0] == '-' {
+			keys = append(keys, bson.E{Key: f[1:], Value: -1})
+			continue
+		}
+		keys = append(keys, bson.E{Key: f, Value: 1})
+	}
+	return mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName("lookup_" + kind).
+			SetPartialFilterExpression(bson.M{"kind": kind}),
+	}
+}
+
+// dropIndex drops the named index, treating a missing index or collection as
+// already dropped.
+func dropIndex(ctx context.Context, coll *mongo.Collection, name string) error {
+	_, err := coll.Indexes().DropOne(ctx, name)
+	var ce mongo.CommandError
+	if errors.As(err, &ce) && (ce.Name == "IndexNotFound" || ce.Name == "NamespaceNotFound") {
+		return nil
+	}
+	return err
+}
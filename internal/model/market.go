@@ -19,7 +19,7 @@ type MarketHistoryRawDoc struct {
 	T          int64            `bson:"t"`
 	UpdatedAt  time.Time        `bson:"updated_at"`
 	Data       MarketHistoryRaw `bson:"data"`
-	MarketId   string           `bson:"marketId"`
+	MarketId   string           `bson:"market"`
 }
 
 type MarketHistory struct {
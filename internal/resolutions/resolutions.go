@@ -0,0 +1,221 @@
+// Package resolutions decides which bar resolutions are ingested and stored.
+// The sets follow the supported_resolutions Injective advertises in its chart
+// config and symbols, as stored by the cron jobs, so new upstream resolutions
+// are picked up without a redeploy. config.ResolutionsConf overrides them.
+package resolutions
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+// Resolver resolves and caches the resolution sets. A nil Resolver returns the
+// defaults in consts.
+type Resolver struct {
+	st   *store.Store
+	conf config.ResolutionsConf
+	ttl  time.Duration
+
+	// loads collapses concurrent reloads of a cache entry; the stores are read
+	// outside mu so requests keep being served from the cache meanwhile.
+	loads singleflight.Group
+	mu    sync.Mutex
+	cache map[string]entry
+}
+
+// entry is one refresh of the sets of a market type: the normalized set of
+// every stored symbol, and under "" the set used for any other symbol.
+type entry struct {
+	sets   map[string][]string
+	at     time.Time
+	failed bool // the refresh failed; it is retried after failTTL
+}
+
+// failTTL is how long a failed refresh is cached, so that an unavailable store
+// is not queried again on every request.
+const failTTL = 30 * time.Second
+
+// New returns a Resolver reading the stored config and symbols from st.
+func New(st *store.Store, c config.ResolutionsConf) *Resolver {
+	ttl := time.Duration(c.RefreshSec) * time.Second
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &Resolver{st: st, conf: c, ttl: ttl, cache: map[string]entry{}}
+}
+
+// Summary returns the summary and summary_all resolutions. Injective does not
+// advertise them, so they come from config or consts.SupportedResolutions.
+func (r *Resolver) Summary() []string {
+	if r != nil && len(r.conf.Summary) > 0 {
+		return r.conf.Summary
+	}
+	return consts.SupportedResolutions
+}
+
+// History returns the stored history resolutions of a market of the given
+// type; for derivatives market is the symbol.
+func (r *Resolver) History(ctx context.Context, typ consts.MarketType, market string) []string {
+	if typ == consts.MarketTypeDerivative {
+		return r.Derivative(ctx, market)
+	}
+	return r.Market(ctx)
+}
+
+// Market returns the spot and market history resolutions: Resolutions.Market
+// if set, else the spot config's supported_resolutions together with those of
+// every spot symbol.
+func (r *Resolver) Market(ctx context.Context) []string {
+	if r == nil {
+		return consts.SupportedMarketResolutions
+	}
+	return r.resolve(ctx, "market", "", r.conf.Market, consts.SupportedMarketResolutions, func(ctx context.Context) (map[string][]string, error) {
+		var out []string
+		cfg, err := r.st.SpotConfigs.Latest(ctx)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		if cfg != nil {
+			out = append(out, cfg.SupportedResolutions...)
+		}
+		names, err := r.st.SpotSymbols.SymbolNames(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			s, err := r.st.SpotSymbols.Symbols(ctx, name)
+			if err != nil {
+				continue
+			}
+			out = append(out, s.SupportedResolutions...)
+		}
+		return map[string][]string{"": out}, nil
+	})
+}
+
+// Derivative returns the history resolutions of a derivative symbol:
+// Resolutions.Derivative if set, else the symbol's supported_resolutions,
+// else the derivative config's. Symbols without a stored symbols document,
+// including the empty symbol, get the config's set.
+func (r *Resolver) Derivative(ctx context.Context, symbol string) []string {
+	def := append(append([]string(nil), consts.SupportedMarketResolutions...), consts.SupportedDerivativeResolutions...)
+	if r == nil {
+		return def
+	}
+	return r.resolve(ctx, "derivative", symbol, r.conf.Derivative, def, func(ctx context.Context) (map[string][]string, error) {
+		sets := map[string][]string{}
+		cfg, err := r.st.DerivativeConfigs.Latest(ctx)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		if cfg != nil {
+			sets[""] = cfg.SupportedResolutions
+		}
+		names, err := r.st.DerivativeSymbols.SymbolNames(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			s, err := r.st.DerivativeSymbols.Symbols(ctx, name)
+			if err != nil {
+				continue
+			}
+			if len(s.SupportedResolutions) > 0 {
+				sets[name] = s.SupportedResolutions
+			}
+		}
+		return sets, nil
+	})
+}
+
+// resolve returns override when set, otherwise the set of symbol in the cached
+// or freshly loaded sets of key, falling back to the "" set and then to def.
+// Every set is cleaned by normalize.
+func (r *Resolver) resolve(ctx context.Context, key, symbol string, override, def []string, load func(ctx context.Context) (map[string][]string, error)) []string {
+	if len(override) > 0 {
+		return r.normalize(override)
+	}
+	r.mu.Lock()
+	e, ok := r.cache[key]
+	r.mu.Unlock()
+	if !ok || time.Since(e.at) >= r.ttlOf(e) {
+		v, _, _ := r.loads.Do(key, func() (any, error) {
+			// 不受发起请求的取消影响：结果由所有等待者共用
+			return r.refresh(context.WithoutCancel(ctx), key, load), nil
+		})
+		e = v.(entry)
+	}
+	if res, ok := e.sets[symbol]; ok {
+		return res
+	}
+	if res, ok := e.sets[""]; ok {
+		return res
+	}
+	return r.normalize(def)
+}
+
+func (r *Resolver) ttlOf(e entry) time.Duration {
+	if e.failed {
+		return min(failTTL, r.ttl)
+	}
+	return r.ttl
+}
+
+// refresh loads the sets of key and caches them. A failed load keeps serving
+// the previous sets, if any, until it is retried.
+func (r *Resolver) refresh(ctx context.Context, key string, load func(ctx context.Context) (map[string][]string, error)) entry {
+	r.mu.Lock()
+	old := r.cache[key]
+	r.mu.Unlock()
+	e := entry{at: time.Now()}
+	upstream, err := load(ctx)
+	if err != nil {
+		logx.Errorf("resolve %s resolutions: %v", key, err)
+		e.sets, e.failed = old.sets, true
+	} else {
+		e.sets = make(map[string][]string, len(upstream))
+		for symbol, in := range upstream {
+			if res := r.normalize(in); len(res) > 0 {
+				e.sets[symbol] = res
+			}
+		}
+		for symbol, res := range e.sets {
+			if prev, ok := old.sets[symbol]; ok && !slices.Equal(prev, res) {
+				logx.Infof("%s %s resolutions changed: %v -> %v", key, symbol, prev, res)
+			}
+		}
+	}
+	r.mu.Lock()
+	r.cache[key] = e
+	r.mu.Unlock()
+	return e
+}
+
+// normalize drops duplicates, excluded resolutions and those without a fixed
+// bar width (months, invalid strings), which cannot be stored as series; they
+// are resampled at query time instead. The result is ordered by bar width.
+func (r *Resolver) normalize(in []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, res := range in {
+		if seen[res] || slices.Contains(r.conf.Exclude, res) || candle.Seconds(res) <= 0 {
+			continue
+		}
+		seen[res] = true
+		out = append(out, res)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return candle.Seconds(out[i]) < candle.Seconds(out[j]) })
+	return out
+}
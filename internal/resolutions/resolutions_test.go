@@ -0,0 +1,82 @@
+package resolutions
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/model"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+func TestResolverFollowsStoredConfigAndSymbols(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	r := New(st, config.ResolutionsConf{})
+	if got := r.Market(ctx); !slices.Equal(got, consts.SupportedMarketResolutions) {
+		t.Fatalf("nothing stored: got %v, want defaults", got)
+	}
+
+	_, _ = st.SpotConfigs.Save(ctx, model.ChartSpotConfig{SupportedResolutions: []string{"1D", "5", "1", "1M", "bogus"}})
+	_, _ = st.SpotSymbols.SaveSymbols(ctx, "INJ/USDT", model.SpotSymbolsRaw{SupportedResolutions: []string{"60", "1"}})
+	_, _ = st.DerivativeConfigs.Save(ctx, model.ChartDerivativeConfig{SupportedResolutions: []string{"15", "60"}})
+	_, _ = st.DerivativeSymbols.SaveSymbols(ctx, "BTC/USDT PERP", model.DerivativeSymbolsRaw{SupportedResolutions: []string{"1", "1W"}})
+
+	r = New(st, config.ResolutionsConf{Exclude: []string{"5"}})
+	if got, want := r.Market(ctx), []string{"1", "60", "1D"}; !slices.Equal(got, want) {
+		t.Fatalf("Market = %v, want %v", got, want)
+	}
+	if got, want := r.Derivative(ctx, "BTC/USDT PERP"), []string{"1", "1W"}; !slices.Equal(got, want) {
+		t.Fatalf("Derivative(symbol) = %v, want %v", got, want)
+	}
+	if got, want := r.History(ctx, consts.MarketTypeDerivative, "ETH/USDT PERP"), []string{"15", "60"}; !slices.Equal(got, want) {
+		t.Fatalf("Derivative without symbols doc = %v, want the config's %v", got, want)
+	}
+
+	// symbols are refreshed: a resolution Injective adds later is picked up
+	if changed, _ := st.DerivativeSymbols.SaveSymbols(ctx, "BTC/USDT PERP", model.DerivativeSymbolsRaw{SupportedResolutions: []string{"1", "5", "1W"}}); !changed {
+		t.Fatalf("changed symbols doc not saved")
+	}
+	r = New(st, config.ResolutionsConf{})
+	if got, want := r.Derivative(ctx, "BTC/USDT PERP"), []string{"1", "5", "1W"}; !slices.Equal(got, want) {
+		t.Fatalf("after refresh: Derivative(symbol) = %v, want %v", got, want)
+	}
+
+	r = New(st, config.ResolutionsConf{Market: []string{"240", "1"}})
+	if got, want := r.Market(ctx), []string{"1", "240"}; !slices.Equal(got, want) {
+		t.Fatalf("override: Market = %v, want %v", got, want)
+	}
+}
+
+func TestResolverCachesPerRefresh(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	_, _ = st.DerivativeConfigs.Save(ctx, model.ChartDerivativeConfig{SupportedResolutions: []string{"15", "60"}})
+	r := New(st, config.ResolutionsConf{})
+	for _, symbol := range []string{"A", "B", "C"} {
+		if got, want := r.Derivative(ctx, symbol), []string{"15", "60"}; !slices.Equal(got, want) {
+			t.Fatalf("Derivative(%s) = %v, want %v", symbol, got, want)
+		}
+	}
+	if len(r.cache) != 1 {
+		t.Fatalf("unknown symbols grew the cache to %d entries", len(r.cache))
+	}
+
+	// a failed load is cached too, not retried on every call
+	loads := 0
+	load := func(context.Context) (map[string][]string, error) {
+		loads++
+		return nil, errors.New("store down")
+	}
+	for i := 0; i < 3; i++ {
+		if got := r.resolve(ctx, "failing", "", nil, []string{"1"}, load); !slices.Equal(got, []string{"1"}) {
+			t.Fatalf("failed load: got %v, want the default", got)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("failed load retried %d times within failTTL", loads)
+	}
+}
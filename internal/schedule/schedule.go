@@ -0,0 +1,50 @@
+// Package schedule decides when background jobs run: at a fixed interval, on a
+// cron expression, or right after the bars of a resolution close.
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
+)
+
+// Schedule returns the next run time strictly after `after`.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// Every runs a job every d, counted from the previous run.
+type Every time.Duration
+
+func (e Every) String() string { return "every " + time.Duration(e).String() }
+
+func (e Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// Aligned runs a job Offset after every bar close of a fixed-width resolution,
+// e.g. at hh:mm:05 for 1-minute bars with a 5s offset.
+type Aligned struct {
+	Width  int64 // bar width in seconds
+	Offset time.Duration
+}
+
+// AlignTo builds an Aligned schedule for a resolution such as "1", "60" or "1D".
+func AlignTo(resolution string, offset time.Duration) (Aligned, error) {
+	width := candle.Seconds(resolution)
+	if width <= 0 {
+		return Aligned{}, fmt.Errorf("schedule: cannot align to resolution %q", resolution)
+	}
+	return Aligned{Width: width, Offset: offset}, nil
+}
+
+func (a Aligned) String() string {
+	return fmt.Sprintf("after each %ds bar close +%s", a.Width, a.Offset)
+}
+
+func (a Aligned) Next(after time.Time) time.Time {
+	base := after.Add(-a.Offset).Unix()
+	next := candle.Align(base, a.Width) + a.Width
+	return time.Unix(next, 0).Add(a.Offset)
+}
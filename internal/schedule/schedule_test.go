@@ -0,0 +1,60 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCronNext(t *testing.T) {
+	cases := []struct {
+		expr, after, want string
+	}{
+		{"*/15 * * * *", "2024-03-10 10:07:30", "2024-03-10 10:15:00"},
+		{"0 * * * *", "2024-03-10 10:00:00", "2024-03-10 11:00:00"},
+		{"30 2 * * 1-5", "2024-03-08 03:00:00", "2024-03-11 02:30:00"}, // Friday -> Monday
+		{"0 0 1 * *", "2024-12-15 00:00:00", "2025-01-01 00:00:00"},
+		{"0 12 * * 7", "2024-03-10 13:00:00", "2024-03-17 12:00:00"}, // 7 is Sunday
+		{"5,35 8-9 * * *", "2024-03-10 08:35:00", "2024-03-10 09:05:00"},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", c.expr, err)
+		}
+		if got := cron.Next(at(c.after)); !got.Equal(at(c.want)) {
+			t.Fatalf("%q after %s = %s, want %s", c.expr, c.after, got, c.want)
+		}
+	}
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+	if never, _ := ParseCron("0 0 30 2 *"); !never.Next(at("2024-01-01 00:00:00")).IsZero() {
+		t.Fatalf("expected no run on February 30th")
+	}
+}
+
+func TestAlignedNext(t *testing.T) {
+	a, err := AlignTo("5", 3*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Next(at("2024-03-10 10:04:59")); !got.Equal(at("2024-03-10 10:05:03")) {
+		t.Fatalf("next = %s", got)
+	}
+	if got := a.Next(at("2024-03-10 10:05:03")); !got.Equal(at("2024-03-10 10:10:03")) {
+		t.Fatalf("next after a run = %s", got)
+	}
+	if _, err := AlignTo("1M", 0); err == nil {
+		t.Fatalf("months have no fixed width and cannot be aligned to")
+	}
+}
@@ -0,0 +1,93 @@
+package schedule
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Job is one scheduled function. Runs of the same job never overlap: the next
+// run time is computed after the previous run returns.
+type Job struct {
+	Name     string
+	Schedule Schedule
+	// Jitter delays every run by a random duration in [0, Jitter) so replicas
+	// and jobs sharing a schedule do not hit Injective at the same instant.
+	Jitter time.Duration
+	Run    func(ctx context.Context)
+}
+
+// Scheduler runs jobs on their schedules until its context is cancelled.
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs []Job
+	wg   sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers a job; it must be called before Start.
+func (s *Scheduler) Add(j Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, j)
+}
+
+// Jobs returns the registered jobs.
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Job(nil), s.jobs...)
+}
+
+// Start runs every job in its own goroutine until ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, j := range s.Jobs() {
+		s.wg.Add(1)
+		go func(j Job) {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+}
+
+// Wait blocks until every job loop has returned after its context was
+// cancelled, including runs in progress.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j Job) {
+	for {
+		next := j.Schedule.Next(time.Now())
+		if next.IsZero() {
+			logx.Errorf("schedule: job %s has no next run, stop scheduling it", j.Name)
+			return
+		}
+		if j.Jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(j.Jitter))))
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.run(ctx, j)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logx.Errorf("schedule: job %s panicked: %v", j.Name, r)
+		}
+	}()
+	j.Run(ctx)
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Candle is one OHLCV bar of a series identified by (market, resolution).
+// For derivative history the market is the TradingView symbol.
+type Candle struct {
+	Market     string
+	Resolution string
+	T          int64
+	O          float64
+	H          float64
+	L          float64
+	C          float64
+	V          float64
+	UpdatedAt  time.Time
+}
+
+// CandleQuery selects bars of one series. From/To bound t inclusively and are
+// ignored when zero; Limit caps the result when positive.
+type CandleQuery struct {
+	Market     string
+	Resolution string
+	From       int64
+	To         int64
+	Limit      int
+}
+
+// SeriesStats summarizes one stored (market, resolution) series.
+type SeriesStats struct {
+	Market     string
+	Resolution string
+	First      int64 // t of the oldest bar
+	Last       int64 // t of the newest bar
+	Count      int64
+}
+
+// CandleRepo persists `kind=history` documents.
+type CandleRepo interface {
+	// Latest returns the bar with the highest t for the series. An empty market
+	// matches any market of the resolution.
+	Latest(ctx context.Context, market, resolution string) (*Candle, error)
+	// Upsert stores bars keyed by (market, resolution, t) in one batch. Bars that
+	// already exist are left untouched, so re-running a fetch is idempotent. It
+	// returns the number of newly inserted bars.
+	Upsert(ctx context.Context, bars []Candle) (int, error)
+	// Overwrite stores bars keyed by (market, resolution, t), replacing the OHLCV
+	// of bars that already exist. It is used for the still-forming tail of a
+	// series. It returns the number of bars inserted or changed.
+	Overwrite(ctx context.Context, bars []Candle) (int, error)
+	// Find returns bars matching q, newest first.
+	Find(ctx context.Context, q CandleQuery) ([]Candle, error)
+	// Series lists every stored series with its bar count and time range.
+	// Only bars with t >= since are counted, so a series without such bars is
+	// left out; 0 summarizes every stored bar.
+	Series(ctx context.Context, since int64) ([]SeriesStats, error)
+	// Times returns the t of the bars of one series with from <= t <= to,
+	// ascending. Zero bounds are ignored as in CandleQuery.
+	Times(ctx context.Context, market, resolution string, from, to int64) ([]int64, error)
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CheckpointRepo persists `kind=checkpoint` documents: the progress of a
+// long-running job (such as a backfill) keyed by an opaque string, so the job
+// can resume where it stopped.
+type CheckpointRepo interface {
+	// Get returns the position stored for key, or ErrNotFound.
+	Get(ctx context.Context, key string) (int64, error)
+	// Set stores the position of key, replacing any previous one.
+	Set(ctx context.Context, key string, pos int64) error
+}
+
+type mongoCheckpointRepo struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoCheckpointRepo) Get(ctx context.Context, key string) (int64, error) {
+	var doc struct {
+		Pos int64 `bson:"pos"`
+	}
+	err := r.coll.FindOne(ctx, bson.M{"kind": KindCheckpoint, "key": key}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, ErrNotFound
+	}
+	return doc.Pos, err
+}
+
+func (r *mongoCheckpointRepo) Set(ctx context.Context, key string, pos int64) error {
+	_, err := r.coll.UpdateOne(ctx,
+		bson.M{"kind": KindCheckpoint, "key": key},
+		bson.M{"$set": bson.M{"pos": pos, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+type memoryCheckpointRepo struct {
+	mu  sync.RWMutex
+	pos map[string]int64
+}
+
+func newMemoryCheckpointRepo() *memoryCheckpointRepo {
+	return &memoryCheckpointRepo{pos: map[string]int64{}}
+}
+
+func (r *memoryCheckpointRepo) Get(_ context.Context, key string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pos, ok := r.pos[key]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return pos, nil
+}
+
+func (r *memoryCheckpointRepo) Set(_ context.Context, key string, pos int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pos[key] = pos
+	return nil
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FenceCollection holds the highest fencing token seen per task lock.
+const FenceCollection = "task_fences"
+
+// ErrStaleFence is returned when a newer holder of a task lock has already
+// written, i.e. the caller's lock expired and was taken over.
+var ErrStaleFence = errors.New("store: stale fencing token")
+
+// Fence is the fencing token of a task lock. Carried in the context of a write
+// (WithFence), it makes candle and snapshot writes conditional: documents
+// record the highest token of each lock that wrote them, and a write with a
+// lower token is refused with ErrStaleFence instead of applied.
+type Fence struct {
+	Name  string
+	Token int64
+}
+
+type fenceKey struct{}
+
+// WithFence returns ctx carrying the fence of lock name. Writes made under it
+// are refused once a newer holder of the lock has written the same document.
+func WithFence(ctx context.Context, name string, token int64) context.Context {
+	return context.WithValue(ctx, fenceKey{}, Fence{Name: name, Token: token})
+}
+
+// fenceOf returns the fence carried by ctx, if any.
+func fenceOf(ctx context.Context) (Fence, bool) {
+	f, ok := ctx.Value(fenceKey{}).(Fence)
+	return f, ok && f.Name != "" && f.Token > 0
+}
+
+// key names the lock in the fences map of a document; Mongo field names
+// cannot contain dots.
+func (f Fence) key() string {
+	return strings.ReplaceAll(f.Name, ".", "_")
+}
+
+// field is the document field holding the token of the fence's lock.
+func (f Fence) field() string {
+	return "fences." + f.key()
+}
+
+// writable matches the documents f may write: those not yet written by a
+// newer holder of the lock, including documents without a token.
+func (f Fence) writable() bson.M {
+	return bson.M{f.field(): bson.M{"$not": bson.M{"$gt": f.Token}}}
+}
+
+// stale reports whether a document holding tokens was written by a newer
+// holder of the lock than f.
+func (f Fence) stale(tokens map[string]int64) bool {
+	return tokens[f.key()] > f.Token
+}
+
+// FenceRepo records the fencing tokens of task locks. A job checks its token
+// before writes the repos do not fence themselves (registry, checkpoints);
+// once a newer token has been seen, older holders are refused.
+type FenceRepo interface {
+	// Check records fence for name and returns ErrStaleFence if a higher
+	// fence was recorded before.
+	Check(ctx context.Context, name string, fence int64) error
+}
+
+type mongoFenceRepo struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoFenceRepo) Check(ctx context.Context, name string, fence int64) error {
+	// 只有记录的 fence 不大于当前值时才能匹配；否则 upsert 会因 _id 冲突失败
+	_, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": name, "fence": bson.M{"$lte": fence}},
+		bson.M{"$set": bson.M{"fence": fence}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrStaleFence
+	}
+	return err
+}
+
+type memoryFenceRepo struct {
+	mu     sync.Mutex
+	fences map[string]int64
+}
+
+func newMemoryFenceRepo() *memoryFenceRepo {
+	return &memoryFenceRepo{fences: map[string]int64{}}
+}
+
+func (r *memoryFenceRepo) Check(_ context.Context, name string, fence int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fences[name] > fence {
+		return ErrStaleFence
+	}
+	r.fences[name] = fence
+	return nil
+}
@@ -0,0 +1,21 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// contentHash fingerprints a payload by its BSON encoding, which is what is
+// stored, so two payloads hash equal exactly when their stored `data` would.
+func contentHash(v any) (string, error) {
+	raw, err := bson.Marshal(struct {
+		Data any `bson:"data"`
+	}{v})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobRunCollection holds one document per cron job run.
+const JobRunCollection = "job_runs"
+
+// JobRunTTL is how long job runs are kept; the TTL index on start is built by
+// internal/migrate.
+const JobRunTTL = 14 * 24 * time.Hour
+
+// JobRun is the outcome of one cron job run.
+type JobRun struct {
+	Job        string    `bson:"job" json:"job"`
+	Start      time.Time `bson:"start" json:"start"`
+	End        time.Time `bson:"end" json:"end"`
+	DurationMs int64     `bson:"duration_ms" json:"durationMs"`
+	Units      int       `bson:"units" json:"units"`
+	Fetched    int       `bson:"fetched" json:"fetched"`
+	Written    int       `bson:"written" json:"written"`
+	Failed     int       `bson:"failed" json:"failed"`
+	// Skipped is set when another replica held the job lock.
+	Skipped bool `bson:"skipped" json:"skipped"`
+	// Manual is set for runs triggered through the admin API or CLI.
+	Manual bool     `bson:"manual,omitempty" json:"manual,omitempty"`
+	Errors []string `bson:"errors,omitempty" json:"errors,omitempty"`
+}
+
+// OK reports whether the run did its work without failures.
+func (r JobRun) OK() bool { return !r.Skipped && r.Failed == 0 }
+
+// JobSummary is the latest state of one job.
+type JobSummary struct {
+	Job  string `bson:"_id" json:"job"`
+	Last JobRun `bson:"last" json:"last"`
+	// LastSuccess is the start of the newest run that was OK; zero if none is
+	// kept.
+	LastSuccess time.Time `bson:"last_success" json:"lastSuccess"`
+	Runs        int       `bson:"runs" json:"runs"` // runs kept
+}
+
+// JobRunRepo persists cron job runs.
+type JobRunRepo interface {
+	Insert(ctx context.Context, run JobRun) error
+	// Runs returns the newest runs of a job, newest first.
+	Runs(ctx context.Context, job string, limit int) ([]JobRun, error)
+	// Jobs summarizes every job with at least one kept run, ordered by name.
+	Jobs(ctx context.Context) ([]JobSummary, error)
+}
+
+type mongoJobRunRepo struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoJobRunRepo) Insert(ctx context.Context, run JobRun) error {
+	_, err := r.coll.InsertOne(ctx, run)
+	return err
+}
+
+func (r *mongoJobRunRepo) Runs(ctx context.Context, job string, limit int) ([]JobRun, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "start", Value: -1}}).SetLimit(int64(limit))
+	cur, err := r.coll.Find(ctx, bson.M{"job": job}, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := []JobRun{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoJobRunRepo) Jobs(ctx context.Context) ([]JobSummary, error) {
+	ok := bson.M{"$and": bson.A{
+		bson.M{"$eq": bson.A{"$skipped", false}},
+		bson.M{"$eq": bson.A{"$failed", 0}},
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "job", Value: 1}, {Key: "start", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$job",
+			"last":         bson.M{"$first": "$$ROOT"},
+			"last_success": bson.M{"$max": bson.M{"$cond": bson.A{ok, "$start", nil}}},
+			"runs":         bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+	cur, err := r.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	out := []JobSummary{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type memoryJobRunRepo struct {
+	mu   sync.RWMutex
+	runs map[string][]JobRun // by job, oldest first
+}
+
+func newMemoryJobRunRepo() *memoryJobRunRepo {
+	return &memoryJobRunRepo{runs: map[string][]JobRun{}}
+}
+
+func (r *memoryJobRunRepo) Insert(_ context.Context, run JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[run.Job] = append(r.runs[run.Job], run)
+	return nil
+}
+
+func (r *memoryJobRunRepo) Runs(_ context.Context, job string, limit int) ([]JobRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	runs := r.runs[job]
+	out := []JobRun{}
+	for i := len(runs) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, runs[i])
+	}
+	return out, nil
+}
+
+func (r *memoryJobRunRepo) Jobs(_ context.Context) ([]JobSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := []JobSummary{}
+	for job, runs := range r.runs {
+		s := JobSummary{Job: job, Last: runs[len(runs)-1], Runs: len(runs)}
+		for _, run := range runs {
+			if run.OK() && run.Start.After(s.LastSuccess) {
+				s.LastSuccess = run.Start
+			}
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Job < out[j].Job })
+	return out, nil
+}
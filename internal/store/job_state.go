@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JobStateCollection holds one document per cron job that was ever paused.
+const JobStateCollection = "job_state"
+
+// JobState is the operator-controlled state of one cron job, shared by every
+// replica.
+type JobState struct {
+	Job       string    `bson:"_id" json:"job"`
+	Paused    bool      `bson:"paused" json:"paused"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// JobStateRepo persists whether cron jobs are paused.
+type JobStateRepo interface {
+	// Paused reports whether job is paused; unknown jobs are not.
+	Paused(ctx context.Context, job string) (bool, error)
+	SetPaused(ctx context.Context, job string, paused bool) error
+	// List returns every job with a stored state, ordered by name.
+	List(ctx context.Context) ([]JobState, error)
+}
+
+type mongoJobStateRepo struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoJobStateRepo) Paused(ctx context.Context, job string) (bool, error) {
+	var s JobState
+	err := r.coll.FindOne(ctx, bson.M{"_id": job}).Decode(&s)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return s.Paused, err
+}
+
+func (r *mongoJobStateRepo) SetPaused(ctx context.Context, job string, paused bool) error {
+	_, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": job},
+		bson.M{"$set": bson.M{"paused": paused, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *mongoJobStateRepo) List(ctx context.Context) ([]JobState, error) {
+	cur, err := r.coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	out := []JobState{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type memoryJobStateRepo struct {
+	mu     sync.RWMutex
+	states map[string]JobState
+}
+
+func newMemoryJobStateRepo() *memoryJobStateRepo {
+	return &memoryJobStateRepo{states: map[string]JobState{}}
+}
+
+func (r *memoryJobStateRepo) Paused(_ context.Context, job string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.states[job].Paused, nil
+}
+
+func (r *memoryJobStateRepo) SetPaused(_ context.Context, job string, paused bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[job] = JobState{Job: job, Paused: paused, UpdatedAt: time.Now()}
+	return nil
+}
+
+func (r *memoryJobStateRepo) List(_ context.Context) ([]JobState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]JobState, 0, len(r.states))
+	for _, s := range r.states {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Job < out[j].Job })
+	return out, nil
+}
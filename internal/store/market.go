@@ -0,0 +1,213 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MarketCollection holds one document per market ever listed on Injective.
+const MarketCollection = "markets"
+
+// MarketEventCollection holds the listing changes detected between snapshots.
+const MarketEventCollection = "market_events"
+
+// Market events.
+const (
+	MarketAdded   = "added"
+	MarketRemoved = "removed"
+)
+
+// Market is the registry entry of one (market type, market). Markets that
+// leave the summary_all snapshot are marked inactive rather than deleted, so
+// their stored candles stay queryable.
+type Market struct {
+	MarketType string    `bson:"market_type" json:"marketType"`
+	Market     string    `bson:"market" json:"market"`
+	Active     bool      `bson:"active" json:"active"`
+	FirstSeen  time.Time `bson:"first_seen" json:"firstSeen"`
+	LastSeen   time.Time `bson:"last_seen" json:"lastSeen"`
+	// RemovedAt is set while the market is inactive.
+	RemovedAt *time.Time `bson:"removed_at,omitempty" json:"removedAt,omitempty"`
+	// Onboarded is false until the history of a newly listed market has been
+	// backfilled from OnboardFrom to OnboardTo (unix seconds).
+	Onboarded   bool  `bson:"onboarded" json:"onboarded"`
+	OnboardFrom int64 `bson:"onboard_from,omitempty" json:"onboardFrom,omitempty"`
+	OnboardTo   int64 `bson:"onboard_to,omitempty" json:"onboardTo,omitempty"`
+}
+
+// MarketEvent is one listing change of a market.
+type MarketEvent struct {
+	MarketType string    `bson:"market_type" json:"marketType"`
+	Market     string    `bson:"market" json:"market"`
+	Event      string    `bson:"event" json:"event"` // MarketAdded or MarketRemoved
+	At         time.Time `bson:"at" json:"at"`
+}
+
+// MarketRepo persists the market registry and its events.
+type MarketRepo interface {
+	// List returns the markets of a type ordered by id; activeOnly drops the
+	// inactive ones.
+	List(ctx context.Context, marketType string, activeOnly bool) ([]Market, error)
+	// Save inserts or replaces m.
+	Save(ctx context.Context, m Market) error
+	// Seen moves LastSeen of the given active markets to at.
+	Seen(ctx context.Context, marketType string, markets []string, at time.Time) error
+	// SetOnboarded marks the history of a market as backfilled.
+	SetOnboarded(ctx context.Context, marketType, market string) error
+	AddEvent(ctx context.Context, e MarketEvent) error
+	// Events returns the newest events, newest first. An empty marketType
+	// lists every type.
+	Events(ctx context.Context, marketType string, limit int) ([]MarketEvent, error)
+}
+
+func marketID(marketType, market string) string { return marketType + ":" + market }
+
+type mongoMarketRepo struct {
+	coll   *mongo.Collection
+	events *mongo.Collection
+}
+
+func (r *mongoMarketRepo) List(ctx context.Context, marketType string, activeOnly bool) ([]Market, error) {
+	filter := bson.M{"market_type": marketType}
+	if activeOnly {
+		filter["active"] = true
+	}
+	cur, err := r.coll.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	out := []Market{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoMarketRepo) Save(ctx context.Context, m Market) error {
+	_, err := r.coll.ReplaceOne(ctx,
+		bson.M{"_id": marketID(m.MarketType, m.Market)},
+		m,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *mongoMarketRepo) Seen(ctx context.Context, marketType string, markets []string, at time.Time) error {
+	if len(markets) == 0 {
+		return nil
+	}
+	_, err := r.coll.UpdateMany(ctx,
+		bson.M{"market_type": marketType, "market": bson.M{"$in": markets}, "active": true},
+		bson.M{"$set": bson.M{"last_seen": at}},
+	)
+	return err
+}
+
+func (r *mongoMarketRepo) SetOnboarded(ctx context.Context, marketType, market string) error {
+	_, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": marketID(marketType, market)},
+		bson.M{"$set": bson.M{"onboarded": true}},
+	)
+	return err
+}
+
+func (r *mongoMarketRepo) AddEvent(ctx context.Context, e MarketEvent) error {
+	_, err := r.events.InsertOne(ctx, e)
+	return err
+}
+
+func (r *mongoMarketRepo) Events(ctx context.Context, marketType string, limit int) ([]MarketEvent, error) {
+	filter := bson.M{}
+	if marketType != "" {
+		filter["market_type"] = marketType
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}}).SetLimit(int64(limit))
+	cur, err := r.events.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := []MarketEvent{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type memoryMarketRepo struct {
+	mu      sync.RWMutex
+	markets map[string]Market
+	events  []MarketEvent // oldest first
+}
+
+func newMemoryMarketRepo() *memoryMarketRepo {
+	return &memoryMarketRepo{markets: map[string]Market{}}
+}
+
+func (r *memoryMarketRepo) List(_ context.Context, marketType string, activeOnly bool) ([]Market, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := []Market{}
+	for _, m := range r.markets {
+		if m.MarketType == marketType && (m.Active || !activeOnly) {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Market < out[j].Market })
+	return out, nil
+}
+
+func (r *memoryMarketRepo) Save(_ context.Context, m Market) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.markets[marketID(m.MarketType, m.Market)] = m
+	return nil
+}
+
+func (r *memoryMarketRepo) Seen(_ context.Context, marketType string, markets []string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, market := range markets {
+		id := marketID(marketType, market)
+		if m, ok := r.markets[id]; ok && m.Active {
+			m.LastSeen = at
+			r.markets[id] = m
+		}
+	}
+	return nil
+}
+
+func (r *memoryMarketRepo) SetOnboarded(_ context.Context, marketType, market string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := marketID(marketType, market)
+	if m, ok := r.markets[id]; ok {
+		m.Onboarded = true
+		r.markets[id] = m
+	}
+	return nil
+}
+
+func (r *memoryMarketRepo) AddEvent(_ context.Context, e MarketEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *memoryMarketRepo) Events(_ context.Context, marketType string, limit int) ([]MarketEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := []MarketEvent{}
+	for i := len(r.events) - 1; i >= 0 && len(out) < limit; i-- {
+		if marketType == "" || r.events[i].MarketType == marketType {
+			out = append(out, r.events[i])
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryCandleRepo struct {
+	mu   sync.RWMutex
+	bars []Candle
+	// fences holds the token per task lock that wrote a bar, keyed like bars
+	fences map[candleKey]map[string]int64
+}
+
+type candleKey struct {
+	market, resolution string
+	t                  int64
+}
+
+func newMemoryCandleRepo() *memoryCandleRepo {
+	return &memoryCandleRepo{fences: map[candleKey]map[string]int64{}}
+}
+
+// fenced reports whether c may be written under ctx and records the fence if
+// so. Like the Mongo repo, a refused bar does not stop the rest of the batch.
+func (r *memoryCandleRepo) fenced(ctx context.Context, c Candle) bool {
+	fence, ok := fenceOf(ctx)
+	if !ok {
+		return true
+	}
+	k := candleKey{c.Market, c.Resolution, c.T}
+	if fence.stale(r.fences[k]) {
+		return false
+	}
+	if r.fences[k] == nil {
+		r.fences[k] = map[string]int64{}
+	}
+	r.fences[k][fence.key()] = fence.Token
+	return true
+}
+
+func (r *memoryCandleRepo) match(c Candle, market, resolution string) bool {
+	return c.Resolution == resolution && (market == "" || c.Market == market)
+}
+
+func (r *memoryCandleRepo) Latest(_ context.Context, market, resolution string) (*Candle, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var latest *Candle
+	for i := range r.bars {
+		c := r.bars[i]
+		if r.match(c, market, resolution) && (latest == nil || c.T > latest.T) {
+			latest = &c
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (r *memoryCandleRepo) Upsert(ctx context.Context, bars []Candle) (int, error) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inserted := 0
+	var err error
+	for _, c := range bars {
+		if r.indexOf(c.Market, c.Resolution, c.T) >= 0 {
+			// 已有的 bar 保持不变，只检查是否已被更新的锁持有者写过
+			if fence, ok := fenceOf(ctx); ok && fence.stale(r.fences[candleKey{c.Market, c.Resolution, c.T}]) {
+				err = ErrStaleFence
+			}
+			continue
+		}
+		r.fenced(ctx, c)
+		if c.UpdatedAt.IsZero() {
+			c.UpdatedAt = now
+		}
+		r.bars = append(r.bars, c)
+		inserted++
+	}
+	return inserted, err
+}
+
+func (r *memoryCandleRepo) Overwrite(ctx context.Context, bars []Candle) (int, error) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := 0
+	var err error
+	for _, c := range bars {
+		if !r.fenced(ctx, c) {
+			err = ErrStaleFence
+			continue
+		}
+		if c.UpdatedAt.IsZero() {
+			c.UpdatedAt = now
+		}
+		i := r.indexOf(c.Market, c.Resolution, c.T)
+		if i < 0 {
+			r.bars = append(r.bars, c)
+			changed++
+			continue
+		}
+		old := r.bars[i]
+		if old.O != c.O || old.H != c.H || old.L != c.L || old.C != c.C || old.V != c.V {
+			changed++
+		}
+		r.bars[i] = c
+	}
+	return changed, err
+}
+
+func (r *memoryCandleRepo) indexOf(market, resolution string, t int64) int {
+	for i, c := range r.bars {
+		if c.Market == market && c.Resolution == resolution && c.T == t {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *memoryCandleRepo) Find(_ context.Context, q CandleQuery) ([]Candle, error) {
+	r.mu.RLock()
+	var out []Candle
+	for _, c := range r.bars {
+		if !r.match(c, q.Market, q.Resolution) {
+			continue
+		}
+		if (q.From > 0 && c.T < q.From) || (q.To > 0 && c.T > q.To) {
+			continue
+		}
+		out = append(out, c)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].T > out[j].T })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+func (r *memoryCandleRepo) Series(_ context.Context, since int64) ([]SeriesStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	index := map[[2]string]int{}
+	var out []SeriesStats
+	for _, c := range r.bars {
+		if c.T < since {
+			continue
+		}
+		k := [2]string{c.Market, c.Resolution}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(out)
+			out = append(out, SeriesStats{Market: c.Market, Resolution: c.Resolution, First: c.T, Last: c.T})
+			i = len(out) - 1
+		}
+		s := &out[i]
+		s.First, s.Last = min(s.First, c.T), max(s.Last, c.T)
+		s.Count++
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Market != out[j].Market {
+			return out[i].Market < out[j].Market
+		}
+		return out[i].Resolution < out[j].Resolution
+	})
+	return out, nil
+}
+
+func (r *memoryCandleRepo) Times(ctx context.Context, market, resolution string, from, to int64) ([]int64, error) {
+	bars, err := r.Find(ctx, CandleQuery{Market: market, Resolution: resolution, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int64, len(bars))
+	for i, c := range bars {
+		out[len(bars)-1-i] = c.T
+	}
+	return out, nil
+}
@@ -0,0 +1,311 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryDoc mirrors one stored document: its key fields and payload.
+type memoryDoc[T any] struct {
+	market     string
+	resolution string
+	symbol     string
+	group      string
+	data       T
+	hash       string
+	updatedAt  time.Time
+	checkedAt  time.Time
+	fences     map[string]int64 // token per task lock that wrote the document
+}
+
+// memoryTable is an append-only list of documents guarded by a mutex. Lookups
+// return the newest match, matching the Mongo repos' updated_at ordering.
+type memoryTable[T any] struct {
+	mu   sync.RWMutex
+	docs []memoryDoc[T]
+}
+
+// insert appends d as the newest document of its series (match). Under a
+// fence it is refused if the newest one was written by a newer lock holder.
+func (t *memoryTable[T]) insert(ctx context.Context, d memoryDoc[T], match func(d memoryDoc[T]) bool) error {
+	fence, fenced := fenceOf(ctx)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if fenced {
+		for i := len(t.docs) - 1; i >= 0; i-- {
+			if match(t.docs[i]) {
+				if fence.stale(t.docs[i].fences) {
+					return ErrStaleFence
+				}
+				break
+			}
+		}
+		d.fences = map[string]int64{fence.key(): fence.Token}
+	}
+	d.updatedAt = time.Now()
+	t.docs = append(t.docs, d)
+	return nil
+}
+
+// save appends d unless the newest document matching match has the same
+// content hash, in which case only its checkedAt is bumped. Under a fence the
+// newest document must not have been written by a newer lock holder.
+func (t *memoryTable[T]) save(ctx context.Context, d memoryDoc[T], match func(d memoryDoc[T]) bool) (bool, error) {
+	hash, err := contentHash(d.data)
+	if err != nil {
+		return false, err
+	}
+	fence, fenced := fenceOf(ctx)
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.docs) - 1; i >= 0; i-- {
+		if match(t.docs[i]) {
+			if fenced && fence.stale(t.docs[i].fences) {
+				return false, ErrStaleFence
+			}
+			if t.docs[i].hash == hash {
+				t.docs[i].checkedAt = now
+				if fenced {
+					if t.docs[i].fences == nil {
+						t.docs[i].fences = map[string]int64{}
+					}
+					t.docs[i].fences[fence.key()] = fence.Token
+				}
+				return false, nil
+			}
+			break
+		}
+	}
+	if fenced {
+		d.fences = map[string]int64{fence.key(): fence.Token}
+	}
+	d.hash, d.updatedAt, d.checkedAt = hash, now, now
+	t.docs = append(t.docs, d)
+	return true, nil
+}
+
+func (t *memoryTable[T]) latest(match func(d memoryDoc[T]) bool) (*memoryDoc[T], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for i := len(t.docs) - 1; i >= 0; i-- {
+		if match(t.docs[i]) {
+			d := t.docs[i]
+			return &d, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (t *memoryTable[T]) all(match func(d memoryDoc[T]) bool) []memoryDoc[T] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var out []memoryDoc[T]
+	for _, d := range t.docs {
+		if match(d) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// prune applies r to each series of the table, series being the documents with
+// equal series(d). Documents are stored oldest first.
+func (t *memoryTable[T]) prune(r Retention, series func(d memoryDoc[T]) string) int64 {
+	if !r.Enabled() {
+		return 0
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	positions := make(map[string][]int)
+	for i := len(t.docs) - 1; i >= 0; i-- {
+		key := series(t.docs[i])
+		positions[key] = append(positions[key], i)
+	}
+	drop := make(map[int]struct{})
+	for _, pos := range positions {
+		stamps := make([]time.Time, len(pos))
+		for i, p := range pos {
+			stamps[i] = t.docs[p].updatedAt
+		}
+		for _, i := range r.expired(stamps, now) {
+			drop[pos[i]] = struct{}{}
+		}
+	}
+	kept := t.docs[:0]
+	for i, d := range t.docs {
+		if _, ok := drop[i]; !ok {
+			kept = append(kept, d)
+		}
+	}
+	t.docs = kept
+	return int64(len(drop))
+}
+
+type memorySnapshotRepo[T any] struct {
+	table memoryTable[[]T]
+}
+
+func newMemorySnapshotRepo[T any]() *memorySnapshotRepo[T] {
+	return &memorySnapshotRepo[T]{}
+}
+
+func (r *memorySnapshotRepo[T]) Latest(_ context.Context, resolution string) ([]T, error) {
+	d, err := r.table.latest(func(d memoryDoc[[]T]) bool { return d.resolution == resolution })
+	if err != nil {
+		return nil, err
+	}
+	return d.data, nil
+}
+
+func (r *memorySnapshotRepo[T]) At(_ context.Context, resolution string, at time.Time) ([]T, error) {
+	d, err := r.table.latest(func(d memoryDoc[[]T]) bool {
+		return d.resolution == resolution && !d.updatedAt.After(at)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.data, nil
+}
+
+func (r *memorySnapshotRepo[T]) Save(ctx context.Context, resolution string, rows []T) (bool, error) {
+	return r.table.save(ctx, memoryDoc[[]T]{resolution: resolution, data: rows}, func(d memoryDoc[[]T]) bool { return d.resolution == resolution })
+}
+
+func (r *memorySnapshotRepo[T]) Prune(_ context.Context, ret Retention) (int64, error) {
+	return r.table.prune(ret, func(d memoryDoc[[]T]) string { return d.resolution }), nil
+}
+
+type memorySummaryRepo[T any] struct {
+	table memoryTable[T]
+}
+
+func newMemorySummaryRepo[T any]() *memorySummaryRepo[T] {
+	return &memorySummaryRepo[T]{}
+}
+
+func (r *memorySummaryRepo[T]) Latest(_ context.Context, market, resolution string) (*T, error) {
+	d, err := r.table.latest(func(d memoryDoc[T]) bool { return d.market == market && d.resolution == resolution })
+	if err != nil {
+		return nil, err
+	}
+	return &d.data, nil
+}
+
+func (r *memorySummaryRepo[T]) Insert(ctx context.Context, market, resolution string, row T) error {
+	return r.table.insert(ctx, memoryDoc[T]{market: market, resolution: resolution, data: row}, func(d memoryDoc[T]) bool {
+		return d.market == market && d.resolution == resolution
+	})
+}
+
+func (r *memorySummaryRepo[T]) Prune(_ context.Context, ret Retention) (int64, error) {
+	return r.table.prune(ret, func(d memoryDoc[T]) string { return d.market + "\x00" + d.resolution }), nil
+}
+
+type memoryConfigRepo[T any] struct {
+	table memoryTable[T]
+}
+
+func newMemoryConfigRepo[T any]() *memoryConfigRepo[T] {
+	return &memoryConfigRepo[T]{}
+}
+
+func (r *memoryConfigRepo[T]) Latest(_ context.Context) (*T, error) {
+	d, err := r.table.latest(func(memoryDoc[T]) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	return &d.data, nil
+}
+
+func (r *memoryConfigRepo[T]) Save(ctx context.Context, cfg T) (bool, error) {
+	return r.table.save(ctx, memoryDoc[T]{data: cfg}, func(memoryDoc[T]) bool { return true })
+}
+
+func (r *memoryConfigRepo[T]) Prune(_ context.Context, ret Retention) (int64, error) {
+	return r.table.prune(ret, func(memoryDoc[T]) string { return "" }), nil
+}
+
+type memorySymbolRepo[I, S any] struct {
+	infos   memoryTable[I]
+	symbols memoryTable[S]
+}
+
+func newMemorySymbolRepo[I, S any]() *memorySymbolRepo[I, S] {
+	return &memorySymbolRepo[I, S]{}
+}
+
+// latestInfos returns the newest symbol_info document per symbol of a group,
+// ordered by symbol like the Mongo repo.
+func (r *memorySymbolRepo[I, S]) latestInfos(group string) []memoryDoc[I] {
+	bySymbol := make(map[string]memoryDoc[I])
+	for _, d := range r.infos.all(func(d memoryDoc[I]) bool { return d.group == group }) {
+		bySymbol[d.symbol] = d
+	}
+	out := make([]memoryDoc[I], 0, len(bySymbol))
+	for _, d := range bySymbol {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].symbol < out[j].symbol })
+	return out
+}
+
+func (r *memorySymbolRepo[I, S]) Infos(_ context.Context, group string) ([]I, error) {
+	docs := r.latestInfos(group)
+	out := make([]I, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, d.data)
+	}
+	return out, nil
+}
+
+func (r *memorySymbolRepo[I, S]) InfoSymbols(_ context.Context, group string) ([]string, error) {
+	var out []string
+	for _, d := range r.latestInfos(group) {
+		out = append(out, d.symbol)
+	}
+	return out, nil
+}
+
+func (r *memorySymbolRepo[I, S]) HasInfo(_ context.Context, symbol, group string) (bool, error) {
+	_, err := r.infos.latest(func(d memoryDoc[I]) bool { return d.symbol == symbol && d.group == group })
+	return err == nil, nil
+}
+
+func (r *memorySymbolRepo[I, S]) SaveInfo(ctx context.Context, symbol, group string, info I) (bool, error) {
+	return r.infos.save(ctx, memoryDoc[I]{symbol: symbol, group: group, data: info}, func(d memoryDoc[I]) bool {
+		return d.symbol == symbol && d.group == group
+	})
+}
+
+func (r *memorySymbolRepo[I, S]) Symbols(_ context.Context, symbol string) (*S, error) {
+	d, err := r.symbols.latest(func(d memoryDoc[S]) bool { return d.symbol == symbol })
+	if err != nil {
+		return nil, err
+	}
+	return &d.data, nil
+}
+
+func (r *memorySymbolRepo[I, S]) SymbolNames(_ context.Context) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, d := range r.symbols.all(func(memoryDoc[S]) bool { return true }) {
+		if !seen[d.symbol] {
+			seen[d.symbol] = true
+			out = append(out, d.symbol)
+		}
+	}
+	return out, nil
+}
+
+func (r *memorySymbolRepo[I, S]) HasSymbols(_ context.Context, symbol string) (bool, error) {
+	_, err := r.symbols.latest(func(d memoryDoc[S]) bool { return d.symbol == symbol })
+	return err == nil, nil
+}
+
+func (r *memorySymbolRepo[I, S]) SaveSymbols(ctx context.Context, symbol string, s S) (bool, error) {
+	return r.symbols.save(ctx, memoryDoc[S]{symbol: symbol, data: s}, func(d memoryDoc[S]) bool { return d.symbol == symbol })
+}
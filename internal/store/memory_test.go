@@ -0,0 +1,304 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/model"
+)
+
+func TestMemoryCandleRepo_FindAndLatest(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if _, err := s.SpotCandles.Upsert(ctx, []Candle{
+		{Market: "m1", Resolution: "1", T: 60, C: 1},
+		{Market: "m1", Resolution: "1", T: 180, C: 3},
+		{Market: "m1", Resolution: "1", T: 120, C: 2},
+		{Market: "m2", Resolution: "1", T: 240, C: 4},
+		{Market: "m1", Resolution: "5", T: 300, C: 5},
+	}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	got, err := s.SpotCandles.Find(ctx, CandleQuery{Market: "m1", Resolution: "1", From: 100, Limit: 5})
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if len(got) != 2 || got[0].T != 180 || got[1].T != 120 {
+		t.Fatalf("unexpected find result: %#v", got)
+	}
+
+	last, err := s.SpotCandles.Latest(ctx, "", "1")
+	if err != nil || last.Market != "m2" {
+		t.Fatalf("latest any market = %#v, %v", last, err)
+	}
+	last, err = s.SpotCandles.Latest(ctx, "m1", "1")
+	if err != nil || last.T != 180 {
+		t.Fatalf("latest m1 = %#v, %v", last, err)
+	}
+	if _, err := s.SpotCandles.Latest(ctx, "m3", "1"); err != ErrNotFound {
+		t.Fatalf("latest missing err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryCandleRepo_UpsertIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	bars := []Candle{
+		{Market: "m1", Resolution: "1", T: 60, C: 1},
+		{Market: "m1", Resolution: "1", T: 120, C: 2},
+	}
+	if n, _ := s.MarketCandles.Upsert(ctx, bars); n != 2 {
+		t.Fatalf("first upsert inserted %d, want 2", n)
+	}
+	rerun := append(bars, Candle{Market: "m1", Resolution: "1", T: 180, C: 3})
+	rerun[0].C = 99
+	if n, _ := s.MarketCandles.Upsert(ctx, rerun); n != 1 {
+		t.Fatalf("re-run inserted %d, want 1", n)
+	}
+	got, _ := s.MarketCandles.Find(ctx, CandleQuery{Market: "m1", Resolution: "1"})
+	if len(got) != 3 {
+		t.Fatalf("got %d bars, want 3", len(got))
+	}
+	if got[2].T != 60 || got[2].C != 1 {
+		t.Fatalf("existing bar must not be overwritten: %#v", got[2])
+	}
+}
+
+func TestMemorySnapshotRepo_LatestWins(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	first := []model.SpotMarketSummary{{MarketSummaryCommon: model.MarketSummaryCommon{MarketID: "a"}}}
+	second := []model.SpotMarketSummary{{MarketSummaryCommon: model.MarketSummaryCommon{MarketID: "b"}}}
+	_, _ = s.SpotSnapshots.Save(ctx, "24h", first)
+	_, _ = s.SpotSnapshots.Save(ctx, "24h", second)
+	_, _ = s.SpotSnapshots.Save(ctx, "week", first)
+
+	rows, err := s.SpotSnapshots.Latest(ctx, "24h")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if len(rows) != 1 || rows[0].MarketID != "b" {
+		t.Fatalf("unexpected rows: %#v", rows)
+	}
+	if _, err := s.SpotSnapshots.Latest(ctx, "month"); err != ErrNotFound {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemorySymbolRepo(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	_, _ = s.DerivativeSymbols.SaveInfo(ctx, "BTC/USDT PERP", "", model.DerivativeSymbolInfoRaw{Symbol: "BTC/USDT PERP"})
+	_, _ = s.DerivativeSymbols.SaveInfo(ctx, "ETH/USDT PERP", "other", model.DerivativeSymbolInfoRaw{Symbol: "ETH/USDT PERP"})
+	_, _ = s.DerivativeSymbols.SaveSymbols(ctx, "BTC/USDT PERP", model.DerivativeSymbolsRaw{Ticker: "BTC"})
+
+	infos, _ := s.DerivativeSymbols.Infos(ctx, "")
+	if len(infos) != 1 || infos[0].Symbol != "BTC/USDT PERP" {
+		t.Fatalf("unexpected infos: %#v", infos)
+	}
+	if ok, _ := s.DerivativeSymbols.HasInfo(ctx, "ETH/USDT PERP", ""); ok {
+		t.Fatalf("symbol_info must be scoped by group")
+	}
+	names, _ := s.DerivativeSymbols.SymbolNames(ctx)
+	if len(names) != 1 || names[0] != "BTC/USDT PERP" {
+		t.Fatalf("unexpected names: %#v", names)
+	}
+	sym, err := s.DerivativeSymbols.Symbols(ctx, "BTC/USDT PERP")
+	if err != nil || sym.Ticker != "BTC" {
+		t.Fatalf("symbols = %#v, %v", sym, err)
+	}
+}
+
+func TestMemoryCandleRepo_OverwriteReplacesLiveBar(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	_, _ = s.SpotCandles.Upsert(ctx, []Candle{{Market: "m1", Resolution: "1", T: 60, H: 1, C: 1, V: 1}})
+
+	n, err := s.SpotCandles.Overwrite(ctx, []Candle{
+		{Market: "m1", Resolution: "1", T: 60, H: 2, C: 2, V: 5},
+		{Market: "m1", Resolution: "1", T: 120, C: 3},
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("overwrite = %d, %v; want 2", n, err)
+	}
+	got, _ := s.SpotCandles.Find(ctx, CandleQuery{Market: "m1", Resolution: "1"})
+	if len(got) != 2 || got[1].C != 2 || got[1].H != 2 || got[1].V != 5 {
+		t.Fatalf("live bar not corrected: %#v", got)
+	}
+	if n, _ := s.SpotCandles.Overwrite(ctx, got[:1]); n != 0 {
+		t.Fatalf("unchanged overwrite reported %d changes", n)
+	}
+}
+
+func TestMemorySnapshotRepo_SaveOnlyOnChange(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	rows := []model.SpotMarketSummary{{MarketSummaryCommon: model.MarketSummaryCommon{MarketID: "a"}}}
+	if changed, err := s.SpotSnapshots.Save(ctx, "24h", rows); err != nil || !changed {
+		t.Fatalf("first save = %v, %v; want inserted", changed, err)
+	}
+	same := []model.SpotMarketSummary{{MarketSummaryCommon: model.MarketSummaryCommon{MarketID: "a"}}}
+	if changed, _ := s.SpotSnapshots.Save(ctx, "24h", same); changed {
+		t.Fatalf("identical payload must not be inserted again")
+	}
+	repo := s.SpotSnapshots.(*memorySnapshotRepo[model.SpotMarketSummary])
+	if n := len(repo.table.docs); n != 1 || repo.table.docs[0].checkedAt.Before(repo.table.docs[0].updatedAt) {
+		t.Fatalf("want one document with bumped checked_at, got %#v", repo.table.docs)
+	}
+	rows[0].MarketID = "b"
+	if changed, _ := s.SpotSnapshots.Save(ctx, "24h", rows); !changed {
+		t.Fatalf("changed payload must be inserted")
+	}
+}
+
+func TestMemorySymbolRepo_InfosReturnsNewestPerSymbol(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	_, _ = s.SpotSymbols.SaveInfo(ctx, "B/USDT", "", model.SpotSymbolInfoRaw{Symbol: "B/USDT", Pricescale: 100})
+	_, _ = s.SpotSymbols.SaveInfo(ctx, "A/USDT", "", model.SpotSymbolInfoRaw{Symbol: "A/USDT", Pricescale: 100})
+	_, _ = s.SpotSymbols.SaveInfo(ctx, "B/USDT", "", model.SpotSymbolInfoRaw{Symbol: "B/USDT", Pricescale: 1000})
+
+	infos, _ := s.SpotSymbols.Infos(ctx, "")
+	if len(infos) != 2 || infos[0].Symbol != "A/USDT" || infos[1].Pricescale != 1000 {
+		t.Fatalf("unexpected infos: %#v", infos)
+	}
+	names, _ := s.SpotSymbols.InfoSymbols(ctx, "")
+	if len(names) != 2 {
+		t.Fatalf("symbol names must be distinct: %v", names)
+	}
+}
+
+func TestMemorySnapshotRepo_At(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	_, _ = s.DerivativeSnapshots.Save(ctx, "24h", []model.DerivativeMarketSummary{{MarketSummaryCommon: model.MarketSummaryCommon{MarketID: "a"}}})
+	repo := s.DerivativeSnapshots.(*memorySnapshotRepo[model.DerivativeMarketSummary])
+	first := time.Now().Add(-time.Hour)
+	repo.table.docs[0].updatedAt = first
+	_, _ = s.DerivativeSnapshots.Save(ctx, "24h", []model.DerivativeMarketSummary{{MarketSummaryCommon: model.MarketSummaryCommon{MarketID: "b"}}})
+
+	rows, err := s.DerivativeSnapshots.At(ctx, "24h", first.Add(time.Minute))
+	if err != nil || len(rows) != 1 || rows[0].MarketID != "a" {
+		t.Fatalf("at after first = %#v, %v", rows, err)
+	}
+	rows, _ = s.DerivativeSnapshots.At(ctx, "24h", time.Now())
+	if len(rows) != 1 || rows[0].MarketID != "b" {
+		t.Fatalf("at now = %#v", rows)
+	}
+	if _, err := s.DerivativeSnapshots.At(ctx, "24h", first.Add(-time.Second)); err != ErrNotFound {
+		t.Fatalf("before first snapshot err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemorySyncStateRepo_AdvanceNeverMovesBack(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if _, err := s.SyncStates.Get(ctx, "spot", "m1", "1"); err != ErrNotFound {
+		t.Fatalf("empty get err = %v, want ErrNotFound", err)
+	}
+	_ = s.SyncStates.Advance(ctx, "spot", "m1", "1", 120)
+	_ = s.SyncStates.Advance(ctx, "spot", "m1", "1", 60)
+	_ = s.SyncStates.Advance(ctx, "spot", "m0", "1", 60)
+	_ = s.SyncStates.Advance(ctx, "derivative", "m1", "1", 60)
+	got, err := s.SyncStates.Get(ctx, "spot", "m1", "1")
+	if err != nil || got.LastT != 120 {
+		t.Fatalf("get = %#v, %v; want last_t 120", got, err)
+	}
+	list, _ := s.SyncStates.List(ctx, "spot")
+	if len(list) != 2 || list[0].Market != "m0" {
+		t.Fatalf("unexpected list: %#v", list)
+	}
+}
+
+func TestMemoryJobRunRepo_JobsSummarizesLastRun(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	t0 := time.Unix(1_700_000_000, 0)
+	_ = s.JobRuns.Insert(ctx, JobRun{Job: "b", Start: t0})
+	_ = s.JobRuns.Insert(ctx, JobRun{Job: "a", Start: t0})
+	_ = s.JobRuns.Insert(ctx, JobRun{Job: "a", Start: t0.Add(time.Minute), Failed: 1})
+	_ = s.JobRuns.Insert(ctx, JobRun{Job: "a", Start: t0.Add(2 * time.Minute), Skipped: true})
+	jobs, err := s.JobRuns.Jobs(ctx)
+	if err != nil || len(jobs) != 2 || jobs[0].Job != "a" {
+		t.Fatalf("jobs = %#v, %v", jobs, err)
+	}
+	if a := jobs[0]; !a.Last.Skipped || a.Runs != 3 || !a.LastSuccess.Equal(t0) {
+		t.Fatalf("unexpected summary of a: %#v", a)
+	}
+	runs, _ := s.JobRuns.Runs(ctx, "a", 2)
+	if len(runs) != 2 || !runs[0].Skipped || runs[1].Failed != 1 {
+		t.Fatalf("runs = %#v", runs)
+	}
+}
+
+func TestMemoryFenceRepo_RefusesOlderHolder(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if err := s.Fences.Check(ctx, "job", 1); err != nil {
+		t.Fatalf("first holder: %v", err)
+	}
+	if err := s.Fences.Check(ctx, "job", 2); err != nil {
+		t.Fatalf("newer holder: %v", err)
+	}
+	if err := s.Fences.Check(ctx, "job", 1); err != ErrStaleFence {
+		t.Fatalf("older holder err = %v, want ErrStaleFence", err)
+	}
+	if err := s.Fences.Check(ctx, "job", 2); err != nil {
+		t.Fatalf("same holder writing again: %v", err)
+	}
+}
+
+func TestMemoryStore_FencedWrites(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	older, newer := WithFence(ctx, "job", 1), WithFence(ctx, "job", 2)
+	bar := Candle{Market: "m1", Resolution: "1", T: 60, C: 1}
+
+	if _, err := s.SpotCandles.Overwrite(newer, []Candle{bar}); err != nil {
+		t.Fatalf("newer holder: %v", err)
+	}
+	stale := bar
+	stale.C = 9
+	if _, err := s.SpotCandles.Overwrite(older, []Candle{stale, {Market: "m1", Resolution: "1", T: 120}}); err != ErrStaleFence {
+		t.Fatalf("older holder err = %v, want ErrStaleFence", err)
+	}
+	got, _ := s.SpotCandles.Find(ctx, CandleQuery{Market: "m1", Resolution: "1"})
+	if len(got) != 2 || got[1].C != 1 {
+		t.Fatalf("stale overwrite applied or batch stopped: %#v", got)
+	}
+	// another lock, or no lock at all, is not fenced by job
+	if _, err := s.SpotCandles.Overwrite(WithFence(ctx, "other", 1), []Candle{stale}); err != nil {
+		t.Fatalf("other lock: %v", err)
+	}
+
+	if _, err := s.SpotConfigs.Save(newer, model.ChartSpotConfig{}); err != nil {
+		t.Fatalf("newer holder save: %v", err)
+	}
+	if _, err := s.SpotConfigs.Save(older, model.ChartSpotConfig{SupportedResolutions: []string{"1"}}); err != ErrStaleFence {
+		t.Fatalf("older holder save err = %v, want ErrStaleFence", err)
+	}
+	if _, err := s.SpotConfigs.Save(newer, model.ChartSpotConfig{SupportedResolutions: []string{"1"}}); err != nil {
+		t.Fatalf("same holder saving again: %v", err)
+	}
+}
+
+func TestMemoryCandleRepo_SeriesSince(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	_, _ = s.SpotCandles.Upsert(ctx, []Candle{
+		{Market: "m1", Resolution: "1", T: 60},
+		{Market: "m1", Resolution: "1", T: 120},
+		{Market: "m1", Resolution: "1", T: 180},
+		{Market: "m2", Resolution: "1", T: 60},
+	})
+	all, _ := s.SpotCandles.Series(ctx, 0)
+	if len(all) != 2 || all[0].Count != 3 {
+		t.Fatalf("Series(0) = %#v", all)
+	}
+	recent, _ := s.SpotCandles.Series(ctx, 120)
+	if len(recent) != 1 || recent[0].Market != "m1" || recent[0].First != 120 || recent[0].Count != 2 {
+		t.Fatalf("Series(120) = %#v", recent)
+	}
+}
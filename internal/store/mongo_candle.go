@@ -0,0 +1,367 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/biya-coin/injective-chronos-go/internal/model"
+)
+
+// mongoCandleRepo stores one bar per document. The three collections differ in
+// the field naming the series (keyField) and in the shape of `data` (encode).
+type mongoCandleRepo struct {
+	coll     *mongo.Collection
+	keyField string
+	encode   func(c Candle) any
+}
+
+func newMongoCandleRepo(coll *mongo.Collection, keyField string, encode func(c Candle) any) *mongoCandleRepo {
+	return &mongoCandleRepo{coll: coll, keyField: keyField, encode: encode}
+}
+
+func encodeSpotCandle(c Candle) any {
+	return model.SpotMarketHistoryRaw{T: c.T, O: c.O, H: c.H, L: c.L, C: c.C, V: c.V}
+}
+
+func encodeDerivativeCandle(c Candle) any {
+	return model.DerivativeHistoryRaw{T: c.T, O: c.O, H: c.H, L: c.L, C: c.C, V: c.V}
+}
+
+func encodeMarketCandle(c Candle) any {
+	return model.MarketHistoryRaw{
+		MarketID:   c.Market,
+		Resolution: c.Resolution,
+		T:          c.T,
+		O:          c.O,
+		H:          c.H,
+		L:          c.L,
+		C:          c.C,
+		V:          c.V,
+	}
+}
+
+// candleDoc is the common part of every history document; the series key is
+// read separately because its field name differs per collection.
+type candleDoc struct {
+	Resolution string                     `bson:"resolution"`
+	UpdatedAt  time.Time                  `bson:"updated_at"`
+	Data       model.SpotMarketHistoryRaw `bson:"data"`
+}
+
+func (r *mongoCandleRepo) filter(market, resolution string) bson.M {
+	f := bson.M{"kind": KindHistory, "resolution": resolution}
+	if market != "" {
+		f[r.keyField] = market
+	}
+	return f
+}
+
+func (r *mongoCandleRepo) decode(raw bson.Raw) (Candle, error) {
+	var doc candleDoc
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return Candle{}, err
+	}
+	market, _ := raw.Lookup(r.keyField).StringValueOK()
+	return Candle{
+		Market:     market,
+		Resolution: doc.Resolution,
+		T:          doc.Data.T,
+		O:          doc.Data.O,
+		H:          doc.Data.H,
+		L:          doc.Data.L,
+		C:          doc.Data.C,
+		V:          doc.Data.V,
+		UpdatedAt:  doc.UpdatedAt,
+	}, nil
+}
+
+func (r *mongoCandleRepo) Latest(ctx context.Context, market, resolution string) (*Candle, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "t", Value: -1}})
+	raw, err := r.coll.FindOne(ctx, r.filter(market, resolution), opts).Raw()
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	c, err := r.decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *mongoCandleRepo) Upsert(ctx context.Context, bars []Candle) (int, error) {
+	res, err := r.bulkUpsert(ctx, bars, "$setOnInsert")
+	if res == nil {
+		return 0, err
+	}
+	return int(res.UpsertedCount), err
+}
+
+func (r *mongoCandleRepo) Overwrite(ctx context.Context, bars []Candle) (int, error) {
+	res, err := r.bulkUpsert(ctx, bars, "$set")
+	if res == nil {
+		return 0, err
+	}
+	return int(res.UpsertedCount + res.ModifiedCount), err
+}
+
+// bulkUpsert writes bars in one unordered batch; op decides whether existing
+// bars keep their payload ($setOnInsert) or are replaced ($set).
+func (r *mongoCandleRepo) bulkUpsert(ctx context.Context, bars []Candle, op string) (*mongo.BulkWriteResult, error) {
+	if len(bars) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	fence, fenced := fenceOf(ctx)
+	models := make([]mongo.WriteModel, 0, len(bars))
+	for _, c := range bars {
+		updatedAt := c.UpdatedAt
+		if updatedAt.IsZero() {
+			updatedAt = now
+		}
+		filter := r.filter(c.Market, c.Resolution)
+		filter["t"] = c.T
+		set := bson.M{"data": r.encode(c), "updated_at": updatedAt}
+		if fenced {
+			// 只匹配未被更新的锁持有者写过的 bar，否则 upsert 会因唯一索引冲突失败
+			for k, v := range fence.writable() {
+				filter[k] = v
+			}
+			set[fence.field()] = fence.Token
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{op: set}).
+			SetUpsert(true))
+	}
+	res, err := r.coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	// Two replicas upserting the same new bar race on the unique index; the loser
+	// gets a duplicate key error although the bar is stored, so it is not an error.
+	// A bar refused by the fence fails the same way and is told apart by staleFence.
+	if err != nil && mongo.IsDuplicateKeyError(err) && !hasNonDuplicateWriteError(err) {
+		if fenced {
+			return res, r.staleFence(ctx, fence, bars, err)
+		}
+		err = nil
+	}
+	return res, err
+}
+
+// staleFence returns ErrStaleFence if any bar whose write failed with err was
+// written by a newer holder of the fence's lock.
+func (r *mongoCandleRepo) staleFence(ctx context.Context, fence Fence, bars []Candle, err error) error {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) {
+		return err
+	}
+	or := make(bson.A, 0, len(bwe.WriteErrors))
+	for _, we := range bwe.WriteErrors {
+		c := bars[we.Index]
+		filter := r.filter(c.Market, c.Resolution)
+		filter["t"] = c.T
+		or = append(or, filter)
+	}
+	n, err := r.coll.CountDocuments(ctx,
+		bson.M{"$or": or, fence.field(): bson.M{"$gt": fence.Token}},
+		options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrStaleFence
+	}
+	return nil
+}
+
+func hasNonDuplicateWriteError(err error) bool {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) {
+		return true
+	}
+	if bwe.WriteConcernError != nil {
+		return true
+	}
+	for _, we := range bwe.WriteErrors {
+		if we.Code != 11000 {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureIndexes creates the unique (kind, market, resolution, t) index that
+// makes Upsert safe across replicas. It only covers history documents, since
+// snapshots share the collection and have no t. Duplicates left by the former
+// count-then-insert ingestion are removed first if the build fails on them.
+func (r *mongoCandleRepo) ensureIndexes(ctx context.Context) error {
+	index := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "kind", Value: 1},
+			{Key: r.keyField, Value: 1},
+			{Key: "resolution", Value: 1},
+			{Key: "t", Value: 1},
+		},
+		Options: options.Index().
+			SetName("uniq_history_" + r.keyField + "_resolution_t").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"kind": KindHistory}),
+	}
+	_, err := r.coll.Indexes().CreateOne(ctx, index)
+	if err == nil || !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	removed, derr := r.dedupe(ctx)
+	if derr != nil {
+		return derr
+	}
+	logx.Infof("store: removed %d duplicate history documents from %s", removed, r.coll.Name())
+	_, err = r.coll.Indexes().CreateOne(ctx, index)
+	return err
+}
+
+// dedupe keeps the earliest document of every (market, resolution, t) bar and
+// deletes the rest.
+func (r *mongoCandleRepo) dedupe(ctx context.Context) (int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"kind": KindHistory}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"m": "$" + r.keyField, "r": "$resolution", "t": "$t"},
+			"ids": bson.M{"$push": "$_id"},
+			"n":   bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"n": bson.M{"$gt": 1}}}},
+	}
+	cur, err := r.coll.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+	var removed int64
+	for cur.Next(ctx) {
+		var group struct {
+			IDs []any `bson:"ids"`
+		}
+		if err := cur.Decode(&group); err != nil {
+			return removed, err
+		}
+		res, err := r.coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": group.IDs[1:]}})
+		if err != nil {
+			return removed, err
+		}
+		removed += res.DeletedCount
+	}
+	return removed, cur.Err()
+}
+
+func (r *mongoCandleRepo) Find(ctx context.Context, q CandleQuery) ([]Candle, error) {
+	f := r.filter(q.Market, q.Resolution)
+	if q.From > 0 || q.To > 0 {
+		rng := bson.M{}
+		if q.From > 0 {
+			rng["$gte"] = q.From
+		}
+		if q.To > 0 {
+			rng["$lte"] = q.To
+		}
+		f["t"] = rng
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "t", Value: -1}})
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+	cur, err := r.coll.Find(ctx, f, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Candle
+	for cur.Next(ctx) {
+		c, err := r.decode(cur.Current)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, cur.Err()
+}
+
+func (r *mongoCandleRepo) Series(ctx context.Context, since int64) ([]SeriesStats, error) {
+	match := bson.M{"kind": KindHistory}
+	if since > 0 {
+		match["t"] = bson.M{"$gte": since}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"m": "$" + r.keyField, "r": "$resolution"},
+			"first": bson.M{"$min": "$t"},
+			"last":  bson.M{"$max": "$t"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.m", Value: 1}, {Key: "_id.r", Value: 1}}}},
+	}
+	cur, err := r.coll.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []SeriesStats
+	for cur.Next(ctx) {
+		var doc struct {
+			ID struct {
+				M string `bson:"m"`
+				R string `bson:"r"`
+			} `bson:"_id"`
+			First int64 `bson:"first"`
+			Last  int64 `bson:"last"`
+			Count int64 `bson:"count"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		out = append(out, SeriesStats{Market: doc.ID.M, Resolution: doc.ID.R, First: doc.First, Last: doc.Last, Count: doc.Count})
+	}
+	return out, cur.Err()
+}
+
+func (r *mongoCandleRepo) Times(ctx context.Context, market, resolution string, from, to int64) ([]int64, error) {
+	f := r.filter(market, resolution)
+	if from > 0 || to > 0 {
+		rng := bson.M{}
+		if from > 0 {
+			rng["$gte"] = from
+		}
+		if to > 0 {
+			rng["$lte"] = to
+		}
+		f["t"] = rng
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "t", Value: 1}}).
+		SetProjection(bson.M{"_id": 0, "t": 1})
+	cur, err := r.coll.Find(ctx, f, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []int64
+	for cur.Next(ctx) {
+		var doc struct {
+			T int64 `bson:"t"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		out = append(out, doc.T)
+	}
+	return out, cur.Err()
+}
@@ -0,0 +1,360 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// dataDoc decodes the `data` payload of a document.
+type dataDoc[T any] struct {
+	UpdatedAt time.Time `bson:"updated_at"`
+	Data      T         `bson:"data"`
+}
+
+// findLatest decodes the newest document matching filter (by updated_at).
+func findLatest[T any](ctx context.Context, coll *mongo.Collection, filter bson.M) (*dataDoc[T], error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	var doc dataDoc[T]
+	if err := coll.FindOne(ctx, filter, opts).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// saveIfChanged inserts key plus data as a new document unless the newest
+// document matching key carries the same content hash, in which case only its
+// checked_at is bumped. Documents written before hashing have no hash and are
+// always superseded. Under a fence (WithFence) the newest document must not
+// have been written by a newer holder of the lock.
+func saveIfChanged(ctx context.Context, coll *mongo.Collection, key bson.M, data any) (bool, error) {
+	hash, err := contentHash(data)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	fence, fenced := fenceOf(ctx)
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetProjection(bson.M{"_id": 1, "hash": 1, "fences": 1})
+	var latest struct {
+		ID     any              `bson:"_id"`
+		Hash   string           `bson:"hash"`
+		Fences map[string]int64 `bson:"fences"`
+	}
+	err = coll.FindOne(ctx, key, opts).Decode(&latest)
+	if err == nil && fenced && fence.stale(latest.Fences) {
+		return false, ErrStaleFence
+	}
+	if err == nil && latest.Hash == hash {
+		filter, set := bson.M{"_id": latest.ID}, bson.M{"checked_at": now}
+		if fenced {
+			for k, v := range fence.writable() {
+				filter[k] = v
+			}
+			set[fence.field()] = fence.Token
+		}
+		res, err := coll.UpdateOne(ctx, filter, bson.M{"$set": set})
+		if err == nil && fenced && res.MatchedCount == 0 {
+			err = ErrStaleFence
+		}
+		return false, err
+	}
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return false, err
+	}
+	doc := versionDoc(ctx, key, data, now)
+	doc["hash"], doc["checked_at"] = hash, now
+	if _, err := coll.InsertOne(ctx, doc); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// insertVersion inserts key plus data as the newest document of its series.
+// Under a fence it is refused if the newest document so far was written by a
+// newer holder of the lock.
+func insertVersion(ctx context.Context, coll *mongo.Collection, key bson.M, data any) error {
+	if fence, ok := fenceOf(ctx); ok {
+		opts := options.FindOne().
+			SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+			SetProjection(bson.M{"fences": 1})
+		var latest struct {
+			Fences map[string]int64 `bson:"fences"`
+		}
+		err := coll.FindOne(ctx, key, opts).Decode(&latest)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		if fence.stale(latest.Fences) {
+			return ErrStaleFence
+		}
+	}
+	_, err := coll.InsertOne(ctx, versionDoc(ctx, key, data, time.Now()))
+	return err
+}
+
+// versionDoc builds a versioned document, recording the fence of ctx if any.
+func versionDoc(ctx context.Context, key bson.M, data any, now time.Time) bson.M {
+	doc := bson.M{"data": data, "updated_at": now}
+	for k, v := range key {
+		doc[k] = v
+	}
+	if fence, ok := fenceOf(ctx); ok {
+		doc["fences"] = bson.M{fence.key(): fence.Token}
+	}
+	return doc
+}
+
+func findAll[T any](ctx context.Context, coll *mongo.Collection, filter bson.M) ([]dataDoc[T], error) {
+	cur, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var docs []dataDoc[T]
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// findSymbolNames returns the distinct symbols of the documents matching
+// filter; symbols keeps one document per change.
+func findSymbolNames(ctx context.Context, coll *mongo.Collection, filter bson.M) ([]string, error) {
+	values, err := coll.Distinct(ctx, "symbol", filter)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func exists(ctx context.Context, coll *mongo.Collection, filter bson.M) (bool, error) {
+	n, err := coll.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// pruneBatch bounds the _id list of a single DeleteMany.
+const pruneBatch = 1000
+
+// prune applies r to every series of kind, a series being the documents that
+// share the values of seriesFields. It relies on the lookup indexes created by
+// the migrations to walk each series by updated_at.
+func prune(ctx context.Context, coll *mongo.Collection, kind string, seriesFields []string, r Retention) (int64, error) {
+	if !r.Enabled() {
+		return 0, nil
+	}
+	group := bson.M{}
+	for _, f := range seriesFields {
+		group[f] = "$" + f
+	}
+	cur, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"kind": kind}}},
+		{{Key: "$group", Value: bson.M{"_id": group}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var series []struct {
+		ID bson.M `bson:"_id"`
+	}
+	if err := cur.All(ctx, &series); err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	var deleted int64
+	for _, s := range series {
+		filter := bson.M{"kind": kind}
+		for _, f := range seriesFields {
+			filter[f] = s.ID[f]
+		}
+		n, err := pruneSeries(ctx, coll, filter, r, now)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+func pruneSeries(ctx context.Context, coll *mongo.Collection, filter bson.M, r Retention, now time.Time) (int64, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetProjection(bson.M{"_id": 1, "updated_at": 1})
+	cur, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	var docs []struct {
+		ID        any       `bson:"_id"`
+		UpdatedAt time.Time `bson:"updated_at"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return 0, err
+	}
+	stamps := make([]time.Time, len(docs))
+	for i, d := range docs {
+		stamps[i] = d.UpdatedAt
+	}
+	var ids []any
+	for _, i := range r.expired(stamps, now) {
+		ids = append(ids, docs[i].ID)
+	}
+	var deleted int64
+	for len(ids) > 0 {
+		n := min(len(ids), pruneBatch)
+		res, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids[:n]}})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += res.DeletedCount
+		ids = ids[n:]
+	}
+	return deleted, nil
+}
+
+type mongoSnapshotRepo[T any] struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoSnapshotRepo[T]) Latest(ctx context.Context, resolution string) ([]T, error) {
+	doc, err := findLatest[[]T](ctx, r.coll, bson.M{"kind": KindSummaryAll, "resolution": resolution})
+	if err != nil {
+		return nil, err
+	}
+	return doc.Data, nil
+}
+
+func (r *mongoSnapshotRepo[T]) At(ctx context.Context, resolution string, at time.Time) ([]T, error) {
+	doc, err := findLatest[[]T](ctx, r.coll, bson.M{
+		"kind":       KindSummaryAll,
+		"resolution": resolution,
+		"updated_at": bson.M{"$lte": at},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc.Data, nil
+}
+
+func (r *mongoSnapshotRepo[T]) Save(ctx context.Context, resolution string, rows []T) (bool, error) {
+	return saveIfChanged(ctx, r.coll, bson.M{"kind": KindSummaryAll, "resolution": resolution}, rows)
+}
+
+func (r *mongoSnapshotRepo[T]) Prune(ctx context.Context, ret Retention) (int64, error) {
+	return prune(ctx, r.coll, KindSummaryAll, []string{"resolution"}, ret)
+}
+
+type mongoSummaryRepo[T any] struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoSummaryRepo[T]) Latest(ctx context.Context, market, resolution string) (*T, error) {
+	doc, err := findLatest[T](ctx, r.coll, bson.M{"kind": KindSummary, "market": market, "resolution": resolution})
+	if err != nil {
+		return nil, err
+	}
+	return &doc.Data, nil
+}
+
+func (r *mongoSummaryRepo[T]) Insert(ctx context.Context, market, resolution string, row T) error {
+	return insertVersion(ctx, r.coll, bson.M{"kind": KindSummary, "market": market, "resolution": resolution}, row)
+}
+
+func (r *mongoSummaryRepo[T]) Prune(ctx context.Context, ret Retention) (int64, error) {
+	return prune(ctx, r.coll, KindSummary, []string{"market", "resolution"}, ret)
+}
+
+type mongoConfigRepo[T any] struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoConfigRepo[T]) Latest(ctx context.Context) (*T, error) {
+	doc, err := findLatest[T](ctx, r.coll, bson.M{"kind": KindConfig})
+	if err != nil {
+		return nil, err
+	}
+	return &doc.Data, nil
+}
+
+func (r *mongoConfigRepo[T]) Save(ctx context.Context, cfg T) (bool, error) {
+	return saveIfChanged(ctx, r.coll, bson.M{"kind": KindConfig}, cfg)
+}
+
+func (r *mongoConfigRepo[T]) Prune(ctx context.Context, ret Retention) (int64, error) {
+	return prune(ctx, r.coll, KindConfig, nil, ret)
+}
+
+type mongoSymbolRepo[I, S any] struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoSymbolRepo[I, S]) Infos(ctx context.Context, group string) ([]I, error) {
+	// symbol_info keeps one document per change; take the newest per symbol
+	cur, err := r.coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"kind": KindSymbolInfo, "group": group}}},
+		{{Key: "$sort", Value: bson.D{{Key: "updated_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$symbol", "doc": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$doc"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "symbol", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var docs []dataDoc[I]
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	out := make([]I, 0, len(docs))
+	for _, d := range docs {
+		out = append(out, d.Data)
+	}
+	return out, nil
+}
+
+func (r *mongoSymbolRepo[I, S]) InfoSymbols(ctx context.Context, group string) ([]string, error) {
+	return findSymbolNames(ctx, r.coll, bson.M{"kind": KindSymbolInfo, "group": group})
+}
+
+func (r *mongoSymbolRepo[I, S]) HasInfo(ctx context.Context, symbol, group string) (bool, error) {
+	return exists(ctx, r.coll, bson.M{"kind": KindSymbolInfo, "symbol": symbol, "group": group})
+}
+
+func (r *mongoSymbolRepo[I, S]) SaveInfo(ctx context.Context, symbol, group string, info I) (bool, error) {
+	return saveIfChanged(ctx, r.coll, bson.M{"kind": KindSymbolInfo, "symbol": symbol, "group": group}, info)
+}
+
+func (r *mongoSymbolRepo[I, S]) Symbols(ctx context.Context, symbol string) (*S, error) {
+	doc, err := findLatest[S](ctx, r.coll, bson.M{"kind": KindSymbols, "symbol": symbol})
+	if err != nil {
+		return nil, err
+	}
+	return &doc.Data, nil
+}
+
+func (r *mongoSymbolRepo[I, S]) SymbolNames(ctx context.Context) ([]string, error) {
+	return findSymbolNames(ctx, r.coll, bson.M{"kind": KindSymbols})
+}
+
+func (r *mongoSymbolRepo[I, S]) HasSymbols(ctx context.Context, symbol string) (bool, error) {
+	return exists(ctx, r.coll, bson.M{"kind": KindSymbols, "symbol": symbol})
+}
+
+func (r *mongoSymbolRepo[I, S]) SaveSymbols(ctx context.Context, symbol string, s S) (bool, error) {
+	return saveIfChanged(ctx, r.coll, bson.M{"kind": KindSymbols, "symbol": symbol}, s)
+}
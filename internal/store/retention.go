@@ -0,0 +1,76 @@
+package store
+
+import "time"
+
+// Retention decides which documents of one snapshot series survive Prune. A
+// zero field disables its rule. Snapshots are stored only when their content
+// changes, so a document stands for its content from its updated_at until the
+// next one; the rules keep the documents that were current at the points in
+// time At can still be asked about.
+type Retention struct {
+	// KeepLatest documents are always kept, whatever their age. Without MaxAge
+	// and HourlyAfter it is a policy of its own: everything older is deleted.
+	KeepLatest int
+	// MaxAge deletes documents superseded more than MaxAge ago. The document
+	// current at now-MaxAge is kept, so At is answered for the last MaxAge and
+	// finds nothing before that.
+	MaxAge time.Duration
+	// HourlyAfter thins documents older than this to the ones that were current
+	// at an hour boundary; At then answers with hour granularity.
+	HourlyAfter time.Duration
+}
+
+// Enabled reports whether r can delete anything.
+func (r Retention) Enabled() bool {
+	return r.KeepLatest > 0 || r.MaxAge > 0 || r.HourlyAfter > 0
+}
+
+// expired returns the positions of the documents to delete. stamps are the
+// updated_at times of one series, newest first; each document was current
+// from its own stamp until the stamp before it.
+func (r Retention) expired(stamps []time.Time, now time.Time) []int {
+	var out []int
+	for i, ts := range stamps {
+		if i < r.KeepLatest {
+			continue
+		}
+		if r.MaxAge <= 0 && r.HourlyAfter <= 0 {
+			// 只配置了 KeepLatest：超出的全部删除
+			out = append(out, i)
+			continue
+		}
+		until := now // 最新的一条至今仍然生效
+		if i > 0 {
+			until = stamps[i-1]
+		}
+		if r.MaxAge > 0 && !until.After(now.Add(-r.MaxAge)) {
+			out = append(out, i)
+			continue
+		}
+		if r.HourlyAfter > 0 && !r.currentAtBoundary(ts, until, now) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// currentAtBoundary reports whether a document current during [from, until)
+// must survive hourly thinning: it is younger than HourlyAfter, or it was
+// current at an hour boundary or at the edges of the thinned and MaxAge
+// windows.
+func (r Retention) currentAtBoundary(from, until, now time.Time) bool {
+	cutoff := now.Add(-r.HourlyAfter)
+	if !from.Before(cutoff) || until.After(cutoff) {
+		return true
+	}
+	if r.MaxAge > 0 {
+		if edge := now.Add(-r.MaxAge); !from.After(edge) && until.After(edge) {
+			return true
+		}
+	}
+	hour := from.Truncate(time.Hour)
+	if hour.Before(from) {
+		hour = hour.Add(time.Hour)
+	}
+	return hour.Before(until)
+}
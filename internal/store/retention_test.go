@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionExpired(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	r := Retention{KeepLatest: 1, MaxAge: 72 * time.Hour, HourlyAfter: 24 * time.Hour}
+	stamps := []time.Time{
+		now.Add(-1 * time.Minute),               // 0 kept: latest
+		now.Add(-2 * time.Hour),                 // 1 kept: younger than HourlyAfter
+		now.Add(-25 * time.Hour),                // 2 kept: current at the HourlyAfter cutoff
+		now.Add(-30*time.Hour + 10*time.Minute), // 3 kept: current at the next hour boundary
+		now.Add(-30*time.Hour + 5*time.Minute),  // 4 dropped: superseded within the hour
+		now.Add(-30*time.Hour - 10*time.Minute), // 5 kept: current at the hour boundary
+		now.Add(-73 * time.Hour),                // 6 kept: current at the MaxAge edge
+		now.Add(-80 * time.Hour),                // 7 dropped: superseded before the MaxAge edge
+	}
+	got := r.expired(stamps, now)
+	if want := []int{4, 7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expired = %v, want %v", got, want)
+	}
+	keep := Retention{KeepLatest: 3}
+	if got, want := keep.expired(stamps, now), []int{3, 4, 5, 6, 7}; !keep.Enabled() || !reflect.DeepEqual(got, want) {
+		t.Fatalf("KeepLatest alone: expired = %v, want %v", got, want)
+	}
+}
+
+func TestSnapshotAtAfterPrune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	hour := now.Add(-48 * time.Hour).Truncate(time.Hour)
+	repo := newMemorySnapshotRepo[string]()
+	for _, d := range []struct {
+		name string
+		at   time.Time
+	}{
+		{"old", now.Add(-200 * time.Hour)},
+		{"mid", now.Add(-100 * time.Hour)},
+		{"a", hour.Add(-40 * time.Minute)},
+		{"b", hour.Add(-20 * time.Minute)},
+		{"c", hour.Add(10 * time.Minute)},
+		{"d", hour.Add(40 * time.Minute)},
+		{"e", now.Add(-time.Minute)},
+	} {
+		repo.table.docs = append(repo.table.docs, memoryDoc[[]string]{resolution: "24h", data: []string{d.name}, updatedAt: d.at})
+	}
+
+	n, _ := repo.Prune(ctx, Retention{MaxAge: 168 * time.Hour, HourlyAfter: 24 * time.Hour})
+	if n != 2 {
+		t.Fatalf("pruned %d, want 2 (a and c)", n)
+	}
+	cases := []struct {
+		at   time.Time
+		want string
+	}{
+		{now.Add(-150 * time.Hour), "old"}, // 仍在 MaxAge 窗口内
+		{hour.Add(5 * time.Minute), "b"},   // 整点时生效的版本
+		{hour.Add(50 * time.Minute), "d"},
+		{now, "e"},
+	}
+	for _, c := range cases {
+		got, err := repo.At(ctx, "24h", c.at)
+		if err != nil || len(got) != 1 || got[0] != c.want {
+			t.Fatalf("At(%s) = %v, %v, want %s", c.at, got, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// SnapshotRepo persists `kind=summary_all` documents: the full market list for a
+// resolution, one document per change. The newest document is the current list.
+type SnapshotRepo[T any] interface {
+	Latest(ctx context.Context, resolution string) ([]T, error)
+	// At returns the list that was current at the given moment: the newest
+	// document stored at or before it. Retention may have thinned older history.
+	At(ctx context.Context, resolution string, at time.Time) ([]T, error)
+	// Save stores rows as a new document only when their content hash differs
+	// from the newest document; otherwise it bumps that document's checked_at.
+	// It reports whether a document was inserted.
+	Save(ctx context.Context, resolution string, rows []T) (bool, error)
+	// Prune deletes the documents of every resolution that r does not retain and
+	// returns how many were deleted.
+	Prune(ctx context.Context, r Retention) (int64, error)
+}
+
+// SummaryRepo persists `kind=summary` documents for a single market.
+type SummaryRepo[T any] interface {
+	Latest(ctx context.Context, market, resolution string) (*T, error)
+	Insert(ctx context.Context, market, resolution string, row T) error
+	// Prune applies r to every (market, resolution) series.
+	Prune(ctx context.Context, r Retention) (int64, error)
+}
+
+// ConfigRepo persists `kind=config` documents, one per change.
+type ConfigRepo[T any] interface {
+	Latest(ctx context.Context) (*T, error)
+	// Save behaves like SnapshotRepo.Save.
+	Save(ctx context.Context, cfg T) (bool, error)
+	Prune(ctx context.Context, r Retention) (int64, error)
+}
+
+// SymbolRepo persists `kind=symbol_info` (I) and `kind=symbols` (S) documents.
+type SymbolRepo[I, S any] interface {
+	// Infos returns the newest symbol_info row of every symbol in a group.
+	Infos(ctx context.Context, group string) ([]I, error)
+	// InfoSymbols returns the symbol names that have symbol_info in a group.
+	InfoSymbols(ctx context.Context, group string) ([]string, error)
+	HasInfo(ctx context.Context, symbol, group string) (bool, error)
+	// SaveInfo behaves like SnapshotRepo.Save for the (symbol, group) series.
+	SaveInfo(ctx context.Context, symbol, group string, info I) (bool, error)
+
+	// Symbols returns the newest symbols document of a symbol.
+	Symbols(ctx context.Context, symbol string) (*S, error)
+	// SymbolNames returns every symbol that has a symbols document.
+	SymbolNames(ctx context.Context) ([]string, error)
+	HasSymbols(ctx context.Context, symbol string) (bool, error)
+	// SaveSymbols behaves like SnapshotRepo.Save for the symbol's series.
+	SaveSymbols(ctx context.Context, symbol string, s S) (bool, error)
+}
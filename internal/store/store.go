@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/biya-coin/injective-chronos-go/internal/model"
+)
+
+// Document kinds. Spot, Derivative and Market collections hold several kinds of
+// documents side by side, distinguished by the `kind` field.
+const (
+	KindHistory    = "history"
+	KindSummaryAll = "summary_all"
+	KindSummary    = "summary"
+	KindConfig     = "config"
+	KindSymbolInfo = "symbol_info"
+	KindSymbols    = "symbols"
+	KindCheckpoint = "checkpoint"
+)
+
+// ErrNotFound is returned when no document matches a lookup.
+var ErrNotFound = errors.New("store: not found")
+
+// Store groups the typed repositories used by the logic and task layers.
+type Store struct {
+	SpotCandles       CandleRepo
+	DerivativeCandles CandleRepo
+	MarketCandles     CandleRepo
+
+	SpotSnapshots       SnapshotRepo[model.SpotMarketSummary]
+	DerivativeSnapshots SnapshotRepo[model.DerivativeMarketSummary]
+
+	SpotSummaries       SummaryRepo[model.SpotMarketSummary]
+	DerivativeSummaries SummaryRepo[model.DerivativeMarketSummary]
+
+	SpotConfigs       ConfigRepo[model.ChartSpotConfig]
+	DerivativeConfigs ConfigRepo[model.ChartDerivativeConfig]
+
+	SpotSymbols       SymbolRepo[model.SpotSymbolInfoRaw, model.SpotSymbolsRaw]
+	DerivativeSymbols SymbolRepo[model.DerivativeSymbolInfoRaw, model.DerivativeSymbolsRaw]
+
+	// Checkpoints lives in the spot collection; see CheckpointRepo.
+	Checkpoints CheckpointRepo
+	// SyncStates lives in the SyncStateCollection of the spot database.
+	SyncStates SyncStateRepo
+	// JobRuns lives in the JobRunCollection of the spot database.
+	JobRuns JobRunRepo
+	// JobStates lives in the JobStateCollection of the spot database.
+	JobStates JobStateRepo
+	// Fences lives in the FenceCollection of the spot database.
+	Fences FenceRepo
+	// Markets lives in the MarketCollection and MarketEventCollection of the
+	// spot database.
+	Markets MarketRepo
+}
+
+// NewMongoStore builds a Store backed by the given collections. market may be nil
+// when no market collection is configured, in which case MarketCandles is nil.
+func NewMongoStore(spot, derivative, market *mongo.Collection) *Store {
+	s := &Store{
+		SpotCandles:       newMongoCandleRepo(spot, "market", encodeSpotCandle),
+		DerivativeCandles: newMongoCandleRepo(derivative, "symbol", encodeDerivativeCandle),
+
+		SpotSnapshots:       &mongoSnapshotRepo[model.SpotMarketSummary]{coll: spot},
+		DerivativeSnapshots: &mongoSnapshotRepo[model.DerivativeMarketSummary]{coll: derivative},
+
+		SpotSummaries:       &mongoSummaryRepo[model.SpotMarketSummary]{coll: spot},
+		DerivativeSummaries: &mongoSummaryRepo[model.DerivativeMarketSummary]{coll: derivative},
+
+		SpotConfigs:       &mongoConfigRepo[model.ChartSpotConfig]{coll: spot},
+		DerivativeConfigs: &mongoConfigRepo[model.ChartDerivativeConfig]{coll: derivative},
+
+		SpotSymbols:       &mongoSymbolRepo[model.SpotSymbolInfoRaw, model.SpotSymbolsRaw]{coll: spot},
+		DerivativeSymbols: &mongoSymbolRepo[model.DerivativeSymbolInfoRaw, model.DerivativeSymbolsRaw]{coll: derivative},
+
+		Checkpoints: &mongoCheckpointRepo{coll: spot},
+		SyncStates:  &mongoSyncStateRepo{coll: spot.Database().Collection(SyncStateCollection)},
+		JobRuns:     &mongoJobRunRepo{coll: spot.Database().Collection(JobRunCollection)},
+		JobStates:   &mongoJobStateRepo{coll: spot.Database().Collection(JobStateCollection)},
+		Fences:      &mongoFenceRepo{coll: spot.Database().Collection(FenceCollection)},
+		Markets: &mongoMarketRepo{
+			coll:   spot.Database().Collection(MarketCollection),
+			events: spot.Database().Collection(MarketEventCollection),
+		},
+	}
+	if market != nil {
+		// Market history used `marketId` until migration 1 renamed it (internal/migrate).
+		s.MarketCandles = newMongoCandleRepo(market, "market", encodeMarketCandle)
+	}
+	return s
+}
+
+// NewMemoryStore builds a Store that keeps everything in process memory. It is
+// meant for tests and local experiments.
+func NewMemoryStore() *Store {
+	return &Store{
+		SpotCandles:       newMemoryCandleRepo(),
+		DerivativeCandles: newMemoryCandleRepo(),
+		MarketCandles:     newMemoryCandleRepo(),
+
+		SpotSnapshots:       newMemorySnapshotRepo[model.SpotMarketSummary](),
+		DerivativeSnapshots: newMemorySnapshotRepo[model.DerivativeMarketSummary](),
+
+		SpotSummaries:       newMemorySummaryRepo[model.SpotMarketSummary](),
+		DerivativeSummaries: newMemorySummaryRepo[model.DerivativeMarketSummary](),
+
+		SpotConfigs:       newMemoryConfigRepo[model.ChartSpotConfig](),
+		DerivativeConfigs: newMemoryConfigRepo[model.ChartDerivativeConfig](),
+
+		SpotSymbols:       newMemorySymbolRepo[model.SpotSymbolInfoRaw, model.SpotSymbolsRaw](),
+		DerivativeSymbols: newMemorySymbolRepo[model.DerivativeSymbolInfoRaw, model.DerivativeSymbolsRaw](),
+
+		Checkpoints: newMemoryCheckpointRepo(),
+		SyncStates:  newMemorySyncStateRepo(),
+		JobRuns:     newMemoryJobRunRepo(),
+		JobStates:   newMemoryJobStateRepo(),
+		Fences:      newMemoryFenceRepo(),
+		Markets:     newMemoryMarketRepo(),
+	}
+}
+
+// indexer is implemented by repos that need Mongo indexes to uphold their
+// guarantees.
+type indexer interface {
+	ensureIndexes(ctx context.Context) error
+}
+
+// EnsureIndexes creates the indexes the repositories rely on. It is a no-op for
+// the memory store.
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	for _, repo := range []CandleRepo{s.SpotCandles, s.DerivativeCandles, s.MarketCandles} {
+		if ix, ok := repo.(indexer); ok {
+			if err := ix.ensureIndexes(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SyncStateCollection holds one document per ingested series.
+const SyncStateCollection = "sync_state"
+
+// SyncState is the ingestion watermark of one (market type, market, resolution)
+// series: LastT is the t of the newest bar stored by incremental ingestion.
+type SyncState struct {
+	MarketType string    `bson:"market_type" json:"marketType"`
+	Market     string    `bson:"market" json:"market"`
+	Resolution string    `bson:"resolution" json:"resolution"`
+	LastT      int64     `bson:"last_t" json:"lastT"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// SyncStateRepo persists per-series ingestion watermarks.
+type SyncStateRepo interface {
+	// Get returns the watermark of a series, or ErrNotFound.
+	Get(ctx context.Context, marketType, market, resolution string) (*SyncState, error)
+	// Advance moves the watermark of a series to lastT; an older lastT never
+	// moves it back.
+	Advance(ctx context.Context, marketType, market, resolution string, lastT int64) error
+	// List returns the watermarks of a market type, ordered by market and
+	// resolution. An empty marketType lists every series.
+	List(ctx context.Context, marketType string) ([]SyncState, error)
+}
+
+func syncStateID(marketType, market, resolution string) string {
+	return fmt.Sprintf("%s:%s:%s", marketType, market, resolution)
+}
+
+type mongoSyncStateRepo struct {
+	coll *mongo.Collection
+}
+
+func (r *mongoSyncStateRepo) Get(ctx context.Context, marketType, market, resolution string) (*SyncState, error) {
+	var s SyncState
+	err := r.coll.FindOne(ctx, bson.M{"_id": syncStateID(marketType, market, resolution)}).Decode(&s)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *mongoSyncStateRepo) Advance(ctx context.Context, marketType, market, resolution string, lastT int64) error {
+	_, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": syncStateID(marketType, market, resolution)},
+		bson.M{
+			"$max":         bson.M{"last_t": lastT},
+			"$currentDate": bson.M{"updated_at": true},
+			"$setOnInsert": bson.M{"market_type": marketType, "market": market, "resolution": resolution},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *mongoSyncStateRepo) List(ctx context.Context, marketType string) ([]SyncState, error) {
+	f := bson.M{}
+	if marketType != "" {
+		f["market_type"] = marketType
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "market", Value: 1}, {Key: "resolution", Value: 1}})
+	cur, err := r.coll.Find(ctx, f, opts)
+	if err != nil {
+		return nil, err
+	}
+	var out []SyncState
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type memorySyncStateRepo struct {
+	mu     sync.RWMutex
+	states map[string]SyncState
+}
+
+func newMemorySyncStateRepo() *memorySyncStateRepo {
+	return &memorySyncStateRepo{states: map[string]SyncState{}}
+}
+
+func (r *memorySyncStateRepo) Get(_ context.Context, marketType, market, resolution string) (*SyncState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.states[syncStateID(marketType, market, resolution)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &s, nil
+}
+
+func (r *memorySyncStateRepo) Advance(_ context.Context, marketType, market, resolution string, lastT int64) error {
+	id := syncStateID(marketType, market, resolution)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.states[id]
+	if !ok {
+		s = SyncState{MarketType: marketType, Market: market, Resolution: resolution, LastT: lastT}
+	}
+	s.LastT = max(s.LastT, lastT)
+	s.UpdatedAt = time.Now()
+	r.states[id] = s
+	return nil
+}
+
+func (r *memorySyncStateRepo) List(_ context.Context, marketType string) ([]SyncState, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []SyncState
+	for _, s := range r.states {
+		if marketType == "" || s.MarketType == marketType {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Market != out[j].Market {
+			return out[i].Market < out[j].Market
+		}
+		return out[i].Resolution < out[j].Resolution
+	})
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package svc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// lifecycle tracks the background work of the process (cron jobs, manually
+// triggered runs) so shutdown can cancel it and wait for it.
+type lifecycle struct {
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (l *lifecycle) init() {
+	l.once.Do(func() { l.ctx, l.cancel = context.WithCancel(context.Background()) })
+}
+
+// Context returns the context of background work; it is cancelled when
+// Shutdown starts.
+func (s *ServiceContext) Context() context.Context {
+	s.life.init()
+	return s.life.ctx
+}
+
+// Go runs fn in a goroutine that Shutdown waits for; fn should return soon
+// after ctx is cancelled.
+func (s *ServiceContext) Go(fn func(ctx context.Context)) {
+	s.life.init()
+	s.life.wg.Add(1)
+	go func() {
+		defer s.life.wg.Done()
+		fn(s.life.ctx)
+	}()
+}
+
+// Shutdown cancels Context and waits up to timeout for the goroutines started
+// by Go. It reports whether all of them returned in time.
+func (s *ServiceContext) Shutdown(timeout time.Duration) bool {
+	s.life.init()
+	s.life.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.life.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Close disconnects Mongo and Redis; call it after Shutdown.
+func (s *ServiceContext) Close(ctx context.Context) {
+	if s.MongoClient != nil {
+		if err := s.MongoClient.Disconnect(ctx); err != nil {
+			logx.Errorf("disconnect mongo: %v", err)
+		}
+	}
+	if s.Redis != nil {
+		if err := s.Redis.Close(); err != nil {
+			logx.Errorf("close redis: %v", err)
+		}
+	}
+}
@@ -12,7 +12,11 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/biya-coin/injective-chronos-go/internal/leader"
 	"github.com/biya-coin/injective-chronos-go/internal/logutil"
+	"github.com/biya-coin/injective-chronos-go/internal/resolutions"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
 )
 
 type ServiceContext struct {
@@ -22,7 +26,18 @@ type ServiceContext struct {
 	SpotColl       *mongo.Collection
 	DerivativeColl *mongo.Collection
 	MarketColl     *mongo.Collection
+	Store          *store.Store
 	HttpClient     *http.Client
+	// Injective is the shared Injective client; its request budget
+	// (Injective.Limit, Injective.Endpoints) covers every job of the process.
+	Injective *injective.Client
+	// Leader elects the replica that runs the cron scheduler (Cron.Leader).
+	Leader *leader.Elector
+	// Resolutions resolves the ingested resolutions from the stored Injective
+	// config and symbols (Resolutions).
+	Resolutions *resolutions.Resolver
+
+	life lifecycle
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
@@ -111,6 +126,9 @@ func NewServiceContext(c config.Config) *ServiceContext {
 		market = db.Collection(c.Mongo.Collections.Market)
 	}
 
+	// Indexes are created by the migrations in internal/migrate
+	st := store.NewMongoStore(spot, derivative, market)
+
 	// HTTP client
 	hc := &http.Client{Timeout: time.Duration(c.Injective.TimeoutMs) * time.Millisecond}
 
@@ -121,13 +139,21 @@ func NewServiceContext(c config.Config) *ServiceContext {
 		logx.SetWriter(logx.NewWriter(sw))
 	}
 
-	return &ServiceContext{
+	svcCtx := &ServiceContext{
 		Config:         c,
 		Redis:          rdb,
 		MongoClient:    client,
 		SpotColl:       spot,
 		DerivativeColl: derivative,
 		MarketColl:     market,
+		Store:          st,
 		HttpClient:     hc,
+		Injective:      injective.NewClient(c.Injective, hc),
+		Leader:         leader.New(rdb, "lock:leader:cron", time.Duration(c.Cron.Leader.TTLSec)*time.Second),
+		Resolutions:    resolutions.New(st, c.Resolutions),
 	}
+	// 配置了多个上游时在每个副本上探测健康与延迟，供请求选择与故障切换；与运行模式无关，
+	// api 模式下管理接口触发的任务与子命令同样使用共享 client
+	svcCtx.Go(svcCtx.Injective.Probe)
+	return svcCtx
 }
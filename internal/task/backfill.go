@@ -0,0 +1,208 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+// BackfillJob describes a historical load of [From, To] (unix seconds,
+// inclusive). An empty Markets means every known market of the type, empty
+// Resolutions the stored resolutions of each market (see Resolutions.History).
+type BackfillJob struct {
+	Type        consts.MarketType
+	Markets     []string
+	Resolutions []string
+	From        int64
+	To          int64
+	// PageBars is the number of bars requested per upstream call.
+	PageBars int
+}
+
+// errBeyondUpstream reports a backfill range older than upstream can return.
+var errBeyondUpstream = errors.New("range older than upstream returns")
+
+// backfillPage fetches the bars of one series with from <= t <= to.
+type backfillPage func(ctx context.Context, market, resolution string, from, to int64) ([]store.Candle, error)
+
+// Backfill walks every (market, resolution) of job page by page and upserts the
+// bars, so bars already stored are left untouched. After each page with bars
+// the covered position is saved to Store.Checkpoints, keyed by From and To:
+// re-running the same job resumes after the last saved page, while a job with
+// another To starts again from From. An empty page saves no position of its
+// own; the next page with bars moves the position past it, so a resume only
+// re-checks the empty pages at the end of the range. It returns the number of
+// newly inserted bars.
+func Backfill(ctx context.Context, svcCtx *svc.ServiceContext, client *injective.Client, job BackfillJob) (int, error) {
+	if job.From <= 0 || job.To < job.From {
+		return 0, fmt.Errorf("backfill: invalid range %d-%d", job.From, job.To)
+	}
+	if job.PageBars <= 0 {
+		job.PageBars = 500
+	}
+	repo, fetch, err := backfillSource(svcCtx, client, job)
+	if err != nil {
+		return 0, err
+	}
+	markets := job.Markets
+	if len(markets) == 0 {
+		if markets, err = backfillMarkets(svcCtx, job.Type); err != nil {
+			return 0, err
+		}
+	}
+	resolutions := func(string) []string { return job.Resolutions }
+	if len(job.Resolutions) == 0 {
+		resolutions = func(market string) []string { return svcCtx.Resolutions.History(ctx, job.Type, market) }
+	}
+	total := 0
+	for _, market := range markets {
+		for _, res := range resolutions(market) {
+			width := candle.Seconds(res)
+			if width <= 0 {
+				return total, fmt.Errorf("backfill: unsupported resolution %q", res)
+			}
+			n, err := backfillSeries(ctx, svcCtx.Store.Checkpoints, repo, fetch, job, market, res, width)
+			total += n
+			if err != nil {
+				return total, fmt.Errorf("backfill %s %s@%s: %w", job.Type, market, res, err)
+			}
+		}
+	}
+	return total, nil
+}
+
+func backfillSeries(ctx context.Context, checkpoints store.CheckpointRepo, repo store.CandleRepo, fetch backfillPage, job BackfillJob, market, res string, width int64) (int, error) {
+	key := fmt.Sprintf("backfill:%s:%s:%s:%d-%d", job.Type, market, res, job.From, job.To)
+	start := job.From
+	if pos, err := checkpoints.Get(ctx, key); err == nil {
+		start = pos + 1
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return 0, err
+	}
+	if start > job.To {
+		logx.Infof("backfill %s %s@%s: already done", job.Type, market, res)
+		return 0, nil
+	}
+	inserted := 0
+	for start <= job.To {
+		if err := ctx.Err(); err != nil {
+			return inserted, err
+		}
+		end := min(start+int64(job.PageBars)*width-1, job.To)
+		bars, err := fetch(ctx, market, res, start, end)
+		if err != nil {
+			return inserted, err
+		}
+		page := bars[:0]
+		oldest := int64(0)
+		for _, b := range bars {
+			if oldest == 0 || b.T < oldest {
+				oldest = b.T
+			}
+			if b.T >= start && b.T <= end {
+				page = append(page, b)
+			}
+		}
+		if len(page) == 0 && oldest > end {
+			// 上游只返回了更新的 K 线：这一页被截断，不能标记为已完成
+			return inserted, fmt.Errorf("%w: page %d-%d, oldest bar returned %d", errBeyondUpstream, start, end, oldest)
+		}
+		if len(page) == 0 {
+			// 空页（如 market 上线之前）本身不记录 checkpoint；之后有数据的页会把进度推过它
+			logx.Infof("backfill %s %s@%s: %d-%d got no bars", job.Type, market, res, start, end)
+			start = end + 1
+			continue
+		}
+		n, err := repo.Upsert(ctx, page)
+		if err != nil {
+			return inserted, err
+		}
+		inserted += n
+		if err := checkpoints.Set(ctx, key, end); err != nil {
+			return inserted, err
+		}
+		logx.Infof("backfill %s %s@%s: %d-%d got %d bars, inserted %d", job.Type, market, res, start, end, len(page), n)
+		start = end + 1
+	}
+	return inserted, nil
+}
+
+// backfillSource returns the candle repo and page fetcher of a market type.
+func backfillSource(svcCtx *svc.ServiceContext, client *injective.Client, job BackfillJob) (store.CandleRepo, backfillPage, error) {
+	switch job.Type {
+	case consts.MarketTypeSpot:
+		return svcCtx.Store.SpotCandles, func(ctx context.Context, market, res string, from, to int64) ([]store.Candle, error) {
+			rows, err := client.SpotMarketHistory(ctx, from, to, market, res, job.PageBars)
+			if err != nil {
+				return nil, err
+			}
+			return spotHistoryCandles(market, res, rows), nil
+		}, nil
+	case consts.MarketTypeDerivative:
+		return svcCtx.Store.DerivativeCandles, func(ctx context.Context, symbol, res string, from, to int64) ([]store.Candle, error) {
+			rows, err := client.DerivativeHistoryRange(ctx, symbol, res, from, to)
+			if err != nil {
+				return nil, err
+			}
+			return derivativeHistoryCandles(symbol, res, rows), nil
+		}, nil
+	case consts.MarketTypeMarket:
+		if svcCtx.Store.MarketCandles == nil {
+			return nil, nil, errors.New("backfill: no market collection configured")
+		}
+		// market/history 只支持 countback（从当前时间往前数），无法按区间翻页：
+		// 每页请求覆盖到 from 的 countback，再按区间过滤。countback 有上限，
+		// 更早的区间直接报错，而不是拿到被截断的数据。
+		return svcCtx.Store.MarketCandles, func(ctx context.Context, market, res string, from, to int64) ([]store.Candle, error) {
+			now := time.Now().Unix()
+			if earliest := marketEarliest(res, now); from < earliest {
+				return nil, fmt.Errorf("%w: market/history returns at most %d bars, back to %d, requested from %d", errBeyondUpstream, marketMaxCountback, earliest, from)
+			}
+			countback := int((now-from)/candle.Seconds(res)) + 1
+			rows, err := client.MarketHistory(ctx, []string{market}, res, countback)
+			if err != nil || len(rows) == 0 {
+				return nil, err
+			}
+			return marketHistoryCandles(res, rows[0]), nil
+		}, nil
+	}
+	return nil, nil, fmt.Errorf("backfill: unknown market type %q", job.Type)
+}
+
+// marketEarliest returns the oldest bar time market/history can still return
+// at now for a resolution.
+func marketEarliest(res string, now int64) int64 {
+	width := candle.Seconds(res)
+	return candle.Align(now, width) - int64(marketMaxCountback-1)*width
+}
+
+// backfillMarkets lists every active market of a type, the same way the
+// history cron jobs do.
+func backfillMarkets(svcCtx *svc.ServiceContext, typ consts.MarketType) ([]string, error) {
+	var markets []string
+	switch typ {
+	case consts.MarketTypeSpot:
+		markets = registeredMarkets(svcCtx, "24h", consts.MarketTypeSpot)
+	case consts.MarketTypeDerivative:
+		symbols, err := getAllDerivativeSymbols(svcCtx)
+		if err != nil {
+			return nil, err
+		}
+		markets = symbols
+	case consts.MarketTypeMarket:
+		markets = getMarketHistoryAllIds(svcCtx, "24h")
+	}
+	if len(markets) == 0 {
+		return nil, fmt.Errorf("backfill: no %s markets found, run the cron once or pass market ids", typ)
+	}
+	return markets, nil
+}
@@ -0,0 +1,110 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/biya-coin/injective-chronos-go/internal/model"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+func TestBackfillPagesAndResumes(t *testing.T) {
+	var calls, failAt int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != consts.SpotHistoryPath {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		if calls == failAt {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		var out model.SpotMarketHistory
+		for ts := from; ts <= to; ts += 60 {
+			out.T = append(out.T, ts)
+			out.O, out.H, out.L, out.C, out.V = append(out.O, 1), append(out.H, 1), append(out.L, 1), append(out.C, 1), append(out.V, 1)
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	defer ts.Close()
+
+	svcCtx := &svc.ServiceContext{Store: store.NewMemoryStore()}
+	client := injective.NewClient(config.InjectiveConf{BaseURL: ts.URL}, ts.Client())
+	job := BackfillJob{Type: consts.MarketTypeSpot, Markets: []string{"m1"}, Resolutions: []string{"1"}, From: 60, To: 600, PageBars: 3}
+
+	failAt = 2
+	n, err := Backfill(context.Background(), svcCtx, client, job)
+	if err == nil || n != 3 {
+		t.Fatalf("expected first run to stop after one page, got n=%d err=%v", n, err)
+	}
+
+	calls, failAt = 0, 0
+	n, err = Backfill(context.Background(), svcCtx, client, job)
+	if err != nil || n != 7 || calls != 3 {
+		t.Fatalf("expected resume to insert 7 bars in 3 calls, got n=%d calls=%d err=%v", n, calls, err)
+	}
+	bars, _ := svcCtx.Store.SpotCandles.Find(context.Background(), store.CandleQuery{Market: "m1", Resolution: "1"})
+	if len(bars) != 10 || bars[0].T != 600 || bars[9].T != 60 {
+		t.Fatalf("unexpected stored bars: %d", len(bars))
+	}
+
+	calls = 0
+	if n, err = Backfill(context.Background(), svcCtx, client, job); err != nil || n != 0 || calls != 0 {
+		t.Fatalf("expected finished job to be a no-op, got n=%d calls=%d err=%v", n, calls, err)
+	}
+
+	// another To is another job: it starts again from From, stored bars stay
+	calls, job.To = 0, 780
+	if n, err = Backfill(context.Background(), svcCtx, client, job); err != nil || n != 3 || calls != 5 {
+		t.Fatalf("expected extended job to start from From, got n=%d calls=%d err=%v", n, calls, err)
+	}
+}
+
+func TestBackfillMarketRejectsTruncatedHistory(t *testing.T) {
+	var calls int
+	now := time.Now().Unix() / 60 * 60
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// 不管 countback 多大，只返回最新的 5 根
+		out := model.MarketHistory{MarketID: "m1", Resolution: "1"}
+		for ts := now - 4*60; ts <= now; ts += 60 {
+			out.T = append(out.T, ts)
+			out.O, out.H, out.L, out.C, out.V = append(out.O, 1), append(out.H, 1), append(out.L, 1), append(out.C, 1), append(out.V, 1)
+		}
+		_ = json.NewEncoder(w).Encode([]model.MarketHistory{out})
+	}))
+	defer ts.Close()
+
+	svcCtx := &svc.ServiceContext{Store: store.NewMemoryStore()}
+	client := injective.NewClient(config.InjectiveConf{BaseURL: ts.URL}, ts.Client())
+	job := BackfillJob{Type: consts.MarketTypeMarket, Markets: []string{"m1"}, Resolutions: []string{"1"}, From: now - 100*60, To: now - 90*60, PageBars: 20}
+
+	n, err := Backfill(context.Background(), svcCtx, client, job)
+	if !errors.Is(err, errBeyondUpstream) || n != 0 || calls != 1 {
+		t.Fatalf("expected truncated page to fail, got n=%d calls=%d err=%v", n, calls, err)
+	}
+	key := fmt.Sprintf("backfill:%s:m1:1:%d-%d", consts.MarketTypeMarket, job.From, job.To)
+	if _, err := svcCtx.Store.Checkpoints.Get(context.Background(), key); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("checkpoint advanced over a truncated page: %v", err)
+	}
+
+	// older than the countback limit: fails without asking upstream
+	calls = 0
+	job.From, job.To = now-2000*60, now-1900*60
+	if _, err = Backfill(context.Background(), svcCtx, client, job); !errors.Is(err, errBeyondUpstream) || calls != 0 {
+		t.Fatalf("expected range beyond countback to fail, got calls=%d err=%v", calls, err)
+	}
+}
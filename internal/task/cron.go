@@ -2,70 +2,57 @@ package task
 
 import (
 	"context"
-	"time"
 
-	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/zeromicro/go-zero/core/logx"
+
+	"github.com/biya-coin/injective-chronos-go/internal/schedule"
 	"github.com/biya-coin/injective-chronos-go/internal/svc"
 )
 
-func StartCron(ctx *svc.ServiceContext) {
-	if !ctx.Config.Cron.Enabled {
+// StartCron schedules every ingestion job plus gap repair, snapshot pruning and
+// the market registry jobs, each on its own schedule (see schedule.go and
+// Cron.Jobs). Scheduling stops when ctx or svcCtx.Context() is cancelled; runs
+// in progress see the cancellation and svcCtx.Shutdown waits for them. With
+// Cron.Leader enabled the scheduler only runs while this replica is the
+// elected leader.
+func StartCron(ctx context.Context, svcCtx *svc.ServiceContext) {
+	if !svcCtx.Config.Cron.Enabled {
 		return
 	}
-	client := injective.NewClient(ctx.Config.Injective, ctx.HttpClient)
-	interval := time.Duration(ctx.Config.Cron.IntervalSec) * time.Second
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			<-ticker.C
-
-			cronInfof("fetching task starting------->spot")
-
-			// Spot
-			go fetchAndStoreSpotConfig(context.Background(), ctx, client)
-			go fetchAndStoreSpotSummaryAll(context.Background(), ctx, client)
-			go fetchAndStoreSpotSummaries(context.Background(), ctx, client)
-			go fetchAndStoreSpotMarketHistory(context.Background(), ctx, client)
-			go fetchAndStoreSpotSymbolInfo(context.Background(), ctx, client)
-			go fetchAndStoreSpotSymbols(context.Background(), ctx, client)
-
+	client := svcCtx.Injective
+	s := schedule.New()
+	for _, j := range allJobs(svcCtx, client) {
+		switch j.Name() {
+		case jobGapRepair:
+			if gaps := svcCtx.Config.Cron.GapRepair; !gaps.Enabled || gaps.IntervalSec <= 0 {
+				continue
+			}
+		case jobPrune:
+			if !svcCtx.Config.Retention.Enabled || svcCtx.Config.Retention.IntervalSec <= 0 {
+				continue
+			}
+		case jobMarketSync, jobMarketOnboard:
+			if !svcCtx.Config.Cron.Markets.Enabled {
+				continue
+			}
 		}
-	}()
-
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			<-ticker.C
-			protect("cron.tick.derivative", func() {
-
-				// Derivative
-				// derivativeResolutions := fetchAndStoreDerivativeConfig(context.Background(), ctx, client)
-				cronInfof("fetching task starting------->derivative")
-				go fetchAndStoreDerivativeSummaryAll(context.Background(), ctx, client)
-				go fetchAndStoreDerivativeSummaries(context.Background(), ctx, client)
-				go fetchAndStoreDerivativeSymbolInfo(context.Background(), ctx, client)
-				go fetchAndStoreDerivativeSymbols(context.Background(), ctx, client)
-				go fetchAndStoreDerivativeHistory(context.Background(), ctx, client)
-				go fetchAndStoreDerivativeConfig(context.Background(), ctx, client)
-
-			})
-		}
-	}()
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			<-ticker.C
-			protect("cron.tick.market", func() {
-
-				cronInfof("fetching task starting------->market")
-
-				// Market
-				fetchAndStoreMarketHistory(context.Background(), ctx, client)
-
-			})
+		logx.Must(addJob(s, svcCtx, j.Name(), func(c context.Context) RunResult { return j.Run(c, svcCtx) }))
+	}
+	run := func(ctx context.Context) {
+		cronInfof("scheduler started")
+		s.Start(ctx)
+		s.Wait()
+		cronInfof("scheduler stopped")
+	}
+	svcCtx.Go(func(life context.Context) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		defer context.AfterFunc(life, cancel)()
+		if !svcCtx.Config.Cron.Leader.Enabled || svcCtx.Leader == nil {
+			run(ctx)
+			return
 		}
-	}()
+		// 只有当选 leader 的副本运行调度器，失去 leader 身份时停止调度并等待正在执行的任务
+		svcCtx.Leader.Run(ctx, run)
+	})
 }
@@ -2,68 +2,18 @@ package task
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"runtime/debug"
-	"time"
+	"sort"
 
 	"github.com/zeromicro/go-zero/core/logx"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/biya-coin/injective-chronos-go/internal/consts"
 	"github.com/biya-coin/injective-chronos-go/internal/model"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
 	"github.com/biya-coin/injective-chronos-go/internal/svc"
 )
 
-// acquireTaskLock 尝试在 wait 时间内基于 Redis 获取分布式任务锁。
-// 成功返回释放函数与 true；失败返回 nil、false。
-func acquireTaskLock(ctx context.Context, svcCtx *svc.ServiceContext, key string, wait time.Duration) (func(), bool) {
-	if key == "" {
-		return nil, false
-	}
-	lockKey := "lock:task:" + key
-
-	// TTL 与重试间隔从配置读取，提供合理默认
-	lockTTL := time.Duration(svcCtx.Config.Redis.LockTTLSeconds) * time.Second
-	if lockTTL <= 0 {
-		lockTTL = 60 * time.Second
-	}
-	retryMs := svcCtx.Config.Redis.RetryMs
-	if retryMs <= 0 {
-		retryMs = 100
-	}
-	retryInterval := time.Duration(retryMs) * time.Millisecond
-
-	// 获取带超时的上下文
-	lockCtx, cancel := context.WithTimeout(ctx, wait)
-	defer cancel()
-
-	for {
-		select {
-		case <-lockCtx.Done():
-			logx.Infof("acquireTaskLock timeout for key=%s", key)
-			return nil, false
-		default:
-		}
-
-		ok, err := svcCtx.Redis.SetNX(lockCtx, lockKey, "1", lockTTL).Result()
-		if err != nil {
-			logx.Errorf("acquireTaskLock SetNX error for key=%s: %v", key, err)
-			return nil, false
-		}
-		if ok {
-			// 成功，提供释放函数
-			release := func() {
-				if _, err := svcCtx.Redis.Del(context.Background(), lockKey).Result(); err != nil {
-					logx.Errorf("releaseTaskLock DEL error for key=%s: %v", key, err)
-				}
-			}
-			return release, true
-		}
-		time.Sleep(retryInterval)
-	}
-}
-
 func parseMarketSummaryAllIds(v []model.MarketSummaryCommon) []string {
 	// logx.Infof("parse market summary all ids: %v", v)
 	var ids []string
@@ -77,32 +27,84 @@ func parseMarketSummaryAllIds(v []model.MarketSummaryCommon) []string {
 
 func getMarketSummaryAllIds(svcCtx *svc.ServiceContext, resolution string, marketType consts.MarketType) []string {
 	// 这里通过mongoch获取resolution对应的marketIds
-	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-	var doc bson.M
-	if marketType == consts.MarketTypeSpot {
-		if err := svcCtx.SpotColl.FindOne(context.Background(), bson.M{"kind": "summary_all", "resolution": resolution}, opts).Decode(&doc); err != nil {
+	var v []model.MarketSummaryCommon
+	switch marketType {
+	case consts.MarketTypeSpot:
+		rows, err := svcCtx.Store.SpotSnapshots.Latest(context.Background(), resolution)
+		if err != nil {
 			logx.Errorf("get market spot summary all ids -> resolution %s: error %v", resolution, err)
 			return nil
 		}
-	}
-	if marketType == consts.MarketTypeDerivative {
-		if err := svcCtx.DerivativeColl.FindOne(context.Background(), bson.M{"kind": "summary_all", "resolution": resolution}, opts).Decode(&doc); err != nil {
+		for _, row := range rows {
+			v = append(v, row.MarketSummaryCommon)
+		}
+	case consts.MarketTypeDerivative:
+		rows, err := svcCtx.Store.DerivativeSnapshots.Latest(context.Background(), resolution)
+		if err != nil {
 			logx.Errorf("get market derivative summary all ids -> resolution %s: error %v", resolution, err)
 			return nil
 		}
+		for _, row := range rows {
+			v = append(v, row.MarketSummaryCommon)
+		}
 	}
-	if doc == nil {
+	if v == nil {
 		logx.Errorf("get market summary all ids -> resolution %s: no data", resolution)
 		return nil
 	}
-	bytes, _ := json.Marshal(doc["data"])
-	var v = []model.MarketSummaryCommon{}
-	err := json.Unmarshal(bytes, &v)
+	return parseMarketSummaryAllIds(v)
+}
+
+// storeCandles 写入一个序列的 bars：最新的 liveBars 根视为未收盘，用上游数据覆盖；
+// 其余的 bars 已收盘，仅在不存在时插入。返回插入或变化的 bars 数。
+func storeCandles(ctx context.Context, repo store.CandleRepo, bars []store.Candle, liveBars int) (int, error) {
+	sort.Slice(bars, func(i, j int) bool { return bars[i].T < bars[j].T })
+	split := len(bars) - liveBars
+	if split < 0 {
+		split = 0
+	}
+	inserted, err := repo.Upsert(ctx, bars[:split])
 	if err != nil {
-		logx.Errorf("get market summary all ids -> resolution %s: error %v", resolution, err)
-		return nil
+		return inserted, err
 	}
-	return parseMarketSummaryAllIds(v)
+	changed, err := repo.Overwrite(ctx, bars[split:])
+	return inserted + changed, err
+}
+
+// seriesWatermark 返回序列最后一次写入的 bar 时间：优先读取 sync_state；sync_state 之前写入的
+// 序列回退到该 market 自己最新的一根 bar。ok=false 表示该序列还没有数据。
+func seriesWatermark(ctx context.Context, svcCtx *svc.ServiceContext, typ consts.MarketType, repo store.CandleRepo, market, res string) (int64, bool) {
+	if s, err := svcCtx.Store.SyncStates.Get(ctx, string(typ), market, res); err == nil {
+		return s.LastT, true
+	} else if !errors.Is(err, store.ErrNotFound) {
+		cronErrorf("get sync_state %s %s@%s: %v", typ, market, res, err)
+	}
+	if last, err := repo.Latest(ctx, market, res); err == nil {
+		return last.T, true
+	}
+	return 0, false
+}
+
+// advanceWatermark 在 bars 写入成功后把序列的 sync_state 推进到最新一根 bar。
+func advanceWatermark(ctx context.Context, svcCtx *svc.ServiceContext, typ consts.MarketType, market, res string, bars []store.Candle) {
+	if len(bars) == 0 {
+		return
+	}
+	var lastT int64
+	for _, b := range bars {
+		lastT = max(lastT, b.T)
+	}
+	if err := svcCtx.Store.SyncStates.Advance(ctx, string(typ), market, res, lastT); err != nil {
+		cronErrorf("advance sync_state %s %s@%s: %v", typ, market, res, err)
+	}
+}
+
+// liveWindowStart 返回需要重新拉取的起始时间：从最新一根 bar 往前回退 liveBars-1 根。
+func liveWindowStart(lastT, barSeconds int64, liveBars int) int64 {
+	if liveBars <= 1 || barSeconds <= 0 {
+		return lastT
+	}
+	return lastT - int64(liveBars-1)*barSeconds
 }
 
 // recoverAndLog recovers from panic and logs error with stack trace and caller info.
@@ -0,0 +1,31 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+func TestSeriesWatermarkIsPerMarket(t *testing.T) {
+	ctx := context.Background()
+	svcCtx := &svc.ServiceContext{Store: store.NewMemoryStore()}
+	repo := svcCtx.Store.MarketCandles
+	_, _ = repo.Upsert(ctx, []store.Candle{
+		{Market: "a", Resolution: "1", T: 60},
+		{Market: "b", Resolution: "1", T: 600},
+	})
+	// a predates sync_state: falls back to its own newest bar, not b's
+	if lastT, ok := seriesWatermark(ctx, svcCtx, consts.MarketTypeMarket, repo, "a", "1"); !ok || lastT != 60 {
+		t.Fatalf("watermark of a = %d, %v; want 60", lastT, ok)
+	}
+	advanceWatermark(ctx, svcCtx, consts.MarketTypeMarket, "a", "1", []store.Candle{{T: 120}, {T: 180}})
+	if lastT, _ := seriesWatermark(ctx, svcCtx, consts.MarketTypeMarket, repo, "a", "1"); lastT != 180 {
+		t.Fatalf("watermark of a = %d; want 180", lastT)
+	}
+	if _, ok := seriesWatermark(ctx, svcCtx, consts.MarketTypeMarket, repo, "c", "1"); ok {
+		t.Fatalf("expected no watermark for a series without data")
+	}
+}
@@ -0,0 +1,121 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+// repairCandleGaps 扫描 spot/derivative/market 各序列最近 LookbackHours 内缺失的 bars，
+// 只重新拉取缺失的区间。上游同样没有数据的区间（如无成交）记录在 checkpoint 中，不再重复拉取。
+// 结果中 Units/Fetched 为拉取的区间数，Written 为补入的 bars 数。
+func repairCandleGaps(ctxBg context.Context, svcCtx *svc.ServiceContext, client *injective.Client) (res RunResult) {
+	res = RunResult{Job: jobGapRepair, Start: time.Now()}
+	defer func() { res.End = time.Now() }()
+	defer recoverRun("repairCandleGaps", &res)
+	lock, ok := acquireTaskLock(ctxBg, svcCtx, jobGapRepair, 3*time.Second)
+	if !ok {
+		cronInfof("repairCandleGaps: acquire lock timeout, skip this run")
+		res.Skipped = true
+		return res
+	}
+	defer lock.release()
+	ctx := lock.ctx
+	cfg := svcCtx.Config.Cron.GapRepair
+	since := time.Now().Add(-time.Duration(cfg.LookbackHours) * time.Hour).Unix()
+	budget := cfg.MaxWindows
+	for _, typ := range []consts.MarketType{consts.MarketTypeSpot, consts.MarketTypeDerivative, consts.MarketTypeMarket} {
+		if budget <= 0 {
+			cronInfof("repairCandleGaps: window budget used up, continue next run")
+			return res
+		}
+		repo, fetch, err := backfillSource(svcCtx, client, BackfillJob{Type: typ})
+		if err != nil {
+			continue
+		}
+		if err := lock.check(ctx); err != nil {
+			res.fail("%s: %v", typ, err)
+			return res
+		}
+		n, filled, err := repairGaps(ctx, svcCtx, typ, repo, fetch, since, budget)
+		budget -= n
+		res.Units += n
+		res.Fetched += n
+		res.Written += filled
+		if err != nil {
+			res.fail("%s: %v", typ, err)
+			cronErrorf("repair %s candle gaps: %v", typ, err)
+		}
+		if n > 0 {
+			cronInfof("repair %s candle gaps: fetched %d windows, inserted %d bars", typ, n, filled)
+		}
+	}
+	return res
+}
+
+// repairGaps fills the gaps after since in every series of repo, fetching at
+// most budget windows. It returns the windows fetched and bars inserted.
+func repairGaps(ctx context.Context, svcCtx *svc.ServiceContext, typ consts.MarketType, repo store.CandleRepo, fetch backfillPage, since int64, budget int) (int, int, error) {
+	// 只统计回看窗口内的 bar，避免每次扫描整个历史集合
+	series, err := repo.Series(ctx, since)
+	if err != nil {
+		return 0, 0, err
+	}
+	// rollup 开启时 spot/market 只从上游拉取 1 分钟 K 线，其余周期由修复后的 1 分钟数据重算
+	rollup := svcCtx.Config.Cron.Rollup.Enabled && typ != consts.MarketTypeDerivative
+	windows, filled := 0, 0
+	for _, s := range series {
+		width := candle.Seconds(s.Resolution)
+		if width <= 0 || s.Last < since || (rollup && s.Resolution != rollupBase) {
+			continue
+		}
+		times, err := repo.Times(ctx, s.Market, s.Resolution, since, 0)
+		if err != nil {
+			return windows, filled, err
+		}
+		for _, gap := range candle.Gaps(times, width) {
+			if windows >= budget {
+				return windows, filled, nil
+			}
+			key := fmt.Sprintf("gap:%s:%s:%s:%d-%d", typ, s.Market, s.Resolution, gap.From, gap.To)
+			if _, err := svcCtx.Store.Checkpoints.Get(ctx, key); err == nil {
+				continue
+			} else if !errors.Is(err, store.ErrNotFound) {
+				return windows, filled, err
+			}
+			windows++
+			bars, err := fetch(ctx, s.Market, s.Resolution, gap.From, gap.To)
+			if err != nil {
+				cronErrorf("repair gap %s %s@%s %d-%d: %v", typ, s.Market, s.Resolution, gap.From, gap.To, err)
+				continue
+			}
+			page := bars[:0]
+			for _, b := range bars {
+				if b.T >= gap.From && b.T <= gap.To {
+					page = append(page, b)
+				}
+			}
+			if len(page) == 0 {
+				// 上游在该区间也没有数据，记录下来避免每次重复拉取
+				_ = svcCtx.Store.Checkpoints.Set(ctx, key, time.Now().Unix())
+				continue
+			}
+			n, err := repo.Upsert(ctx, page)
+			if err != nil {
+				return windows, filled, err
+			}
+			filled += n
+			if rollup {
+				rollupCandles(ctx, repo, s.Market, gap.From, svcCtx.Resolutions.Market(ctx), nil)
+			}
+		}
+	}
+	return windows, filled, nil
+}
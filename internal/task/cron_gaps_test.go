@@ -0,0 +1,60 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/biya-coin/injective-chronos-go/internal/model"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+func TestRepairGapsFetchesOnlyMissingWindows(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		var out model.SpotMarketHistory
+		// upstream has no trades in the second gap
+		for ts := from; ts <= to && from < 400; ts += 60 {
+			out.T = append(out.T, ts)
+			out.O, out.H, out.L, out.C, out.V = append(out.O, 1), append(out.H, 1), append(out.L, 1), append(out.C, 1), append(out.V, 1)
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	svcCtx := &svc.ServiceContext{Store: store.NewMemoryStore()}
+	client := injective.NewClient(config.InjectiveConf{BaseURL: ts.URL}, ts.Client())
+	repo := svcCtx.Store.SpotCandles
+	var bars []store.Candle
+	for _, t := range []int64{60, 120, 360, 420, 900} {
+		bars = append(bars, store.Candle{Market: "m1", Resolution: "1", T: t})
+	}
+	_, _ = repo.Upsert(ctx, bars)
+
+	_, fetch, _ := backfillSource(svcCtx, client, BackfillJob{Type: consts.MarketTypeSpot})
+	windows, filled, err := repairGaps(ctx, svcCtx, consts.MarketTypeSpot, repo, fetch, 1, 10)
+	if err != nil || windows != 2 || filled != 3 {
+		t.Fatalf("expected 2 windows and 3 bars, got windows=%d filled=%d err=%v", windows, filled, err)
+	}
+	times, _ := repo.Times(ctx, "m1", "1", 0, 0)
+	if len(times) != 8 {
+		t.Fatalf("unexpected times after repair: %v", times)
+	}
+
+	// the window upstream has no data for is not fetched again
+	calls = 0
+	if windows, _, _ = repairGaps(ctx, svcCtx, consts.MarketTypeSpot, repo, fetch, 1, 10); windows != 0 || calls != 0 {
+		t.Fatalf("expected no refetch, got windows=%d calls=%d", windows, calls)
+	}
+}
@@ -2,22 +2,18 @@ package task
 
 import (
 	"context"
-	"strconv"
-	"time"
-
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/biya-coin/injective-chronos-go/internal/consts"
 	"github.com/biya-coin/injective-chronos-go/internal/injective"
 	"github.com/biya-coin/injective-chronos-go/internal/model"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
 	"github.com/biya-coin/injective-chronos-go/internal/svc"
 )
 
 func getMarketHistoryAllIds(svcCtx *svc.ServiceContext, resolution string) []string {
-	// get market ids from summary_all snapshots for the configured resolution
-	spotIds := getMarketSummaryAllIds(svcCtx, resolution, consts.MarketTypeSpot)
-	derivativeIds := getMarketSummaryAllIds(svcCtx, resolution, consts.MarketTypeDerivative)
+	// get active market ids from the market registry, or the summary_all snapshots before its first sync
+	spotIds := registeredMarkets(svcCtx, resolution, consts.MarketTypeSpot)
+	derivativeIds := registeredMarkets(svcCtx, resolution, consts.MarketTypeDerivative)
 	if len(spotIds) == 0 && len(derivativeIds) == 0 {
 		cronErrorf("market history -> resolution %s: no market ids from summary_all", resolution)
 		return nil
@@ -45,75 +41,68 @@ func getMarketHistoryAllIds(svcCtx *svc.ServiceContext, resolution string) []str
 	return marketIDs
 }
 
-// fetchAndStoreMarketHistory aggregates market IDs from spot and derivative summary_all, then fetches
-// market candle history in batches and stores records to Mongo `MarketColl`.
-func fetchAndStoreMarketHistory(ctxBg context.Context, svcCtx *svc.ServiceContext, client *injective.Client) {
-	var countback = 0
-	for _, res := range consts.SupportedMarketResolutions {
+// marketJobs 返回 market（聚合 spot/derivative marketIds）的拉取任务；未配置 market 集合时为空。
+func marketJobs(svcCtx *svc.ServiceContext, client *injective.Client) []job {
+	if svcCtx.Store.MarketCandles == nil {
+		cronErrorf("market history: no market collection configured, skip")
+		return nil
+	}
+	return []job{marketHistoryPipeline(svcCtx, client)}
+}
 
-		// 这里需要动态去算最新改的countback
-		// 先查数据库是否有数据，如果没有，那countback就是0
-		opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
-		var doc model.MarketHistoryRawDoc
-		if err := svcCtx.MarketColl.FindOne(context.Background(), bson.M{"kind": "history", "resolution": res}, opts).Decode(&doc); err != nil {
-			countback = 0
-		} else {
-			// 当前时间的时间戳减去最新的一条数据的timestamp，得到时间差，再除以resolution，得到countback
-			// 加10是为了防止数据不足，导致countback为0
-			resolution, _ := strconv.ParseInt(res, 10, 64)
-			countback = int((time.Now().Unix()-doc.Data.T)/resolution) + 10
-		}
-		if countback > 1440 {
-			countback = 1440
-		}
-		marketIDs := getMarketHistoryAllIds(svcCtx, "24h")
-		// batch request to avoid very long query string
-		for _, marketId := range marketIDs {
-			protect("market.history.batch", func() {
-				rows, err := client.MarketHistory(ctxBg, []string{marketId}, res, countback)
-				if err != nil {
-					cronErrorf("fetch market history -> res %s marketId %s: %v", res, marketId, err)
-					return
+// marketMaxCountback 是 market/history 单次最多返回的根数，更早的 K 线无法取回。
+const marketMaxCountback = 1440
+
+// marketHistoryPipeline 拉取 market K 线，每次请求一个 marketId，避免 query string 过长。
+func marketHistoryPipeline(svcCtx *svc.ServiceContext, client *injective.Client) job {
+	liveBars := svcCtx.Config.Cron.LiveBars
+	units := marketUnits(func() []string { return historyResolutions(svcCtx) }, func(context.Context, string) ([]string, error) {
+		return getMarketHistoryAllIds(svcCtx, "24h"), nil
+	})
+	return historyPipeline(svcCtx, consts.MarketTypeMarket, "market_history_fetch", svcCtx.Store.MarketCandles, units, historySource[[]model.MarketHistory]{
+		fetch: func(ctx context.Context, u unit, lastT int64, ok bool) ([]model.MarketHistory, error) {
+			// 没有数据时 countback 为 0（拉取全部）；否则加 10 防止数据不足，
+			// 再加 liveBars，确保最新的几根未收盘 bars 会被重新拉取并覆盖
+			countback := 0
+			if ok {
+				countback = min(countbackSince(lastT, u.Resolution, 10+liveBars), marketMaxCountback)
+			}
+			return client.MarketHistory(ctx, []string{u.Market}, u.Resolution, countback)
+		},
+		candles: func(u unit, rows []model.MarketHistory) []store.Candle {
+			var bars []store.Candle
+			for _, row := range rows {
+				if row.MarketID == u.Market {
+					bars = append(bars, marketHistoryCandles(u.Resolution, row)...)
 				}
-				for _, row := range rows {
-					for t_index := 0; t_index < len(row.T); t_index++ {
-						// 先查询是否已存在该条记录，不存在则插入
-						filter := bson.M{
-							"kind":       "history",
-							"marketId":   row.MarketID,
-							"resolution": res,
-							"t":          row.T[t_index],
-						}
-						count, err := svcCtx.MarketColl.CountDocuments(ctxBg, filter)
-						if err != nil {
-							cronErrorf("count market history %s@%s: %v", row.MarketID, res, err)
-							continue
-						}
-						if count == 0 {
-							_, e := svcCtx.MarketColl.InsertOne(ctxBg, bson.M{
-								"kind":       "history",
-								"marketId":   row.MarketID,
-								"resolution": res,
-								"data": model.MarketHistoryRaw{
-									MarketID:   row.MarketID,
-									Resolution: res,
-									T:          row.T[t_index],
-									O:          row.O[t_index],
-									H:          row.H[t_index],
-									L:          row.L[t_index],
-									C:          row.C[t_index],
-									V:          row.V[t_index],
-								},
-								"t":          row.T[t_index],
-								"updated_at": time.Now(),
-							})
-							if e != nil {
-								cronErrorf("insert market history %s@%s: %v", row.MarketID, res, e)
-							}
-						}
-					}
+			}
+			return bars
+		},
+		verify: func(market string) upstreamBars {
+			return func(ctx context.Context, resolution string, countback int) ([]store.Candle, error) {
+				rows, err := client.MarketHistory(ctx, []string{market}, resolution, countback)
+				if err != nil || len(rows) == 0 {
+					return nil, err
 				}
-			})
-		}
+				return marketHistoryCandles(resolution, rows[0]), nil
+			}
+		},
+	})
+}
+
+func marketHistoryCandles(resolution string, row model.MarketHistory) []store.Candle {
+	bars := make([]store.Candle, 0, len(row.T))
+	for t_index := 0; t_index < len(row.T); t_index++ {
+		bars = append(bars, store.Candle{
+			Market:     row.MarketID,
+			Resolution: resolution,
+			T:          row.T[t_index],
+			O:          row.O[t_index],
+			H:          row.H[t_index],
+			L:          row.L[t_index],
+			C:          row.C[t_index],
+			V:          row.V[t_index],
+		})
 	}
+	return bars
 }
@@ -0,0 +1,65 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+func retentionOf(p config.RetentionPolicy) store.Retention {
+	return store.Retention{
+		KeepLatest:  p.KeepLatest,
+		MaxAge:      time.Duration(p.MaxAgeHours) * time.Hour,
+		HourlyAfter: time.Duration(p.HourlyAfterHours) * time.Hour,
+	}
+}
+
+// pruneSnapshots 按 Retention 配置清理 summary_all / summary / config 历史快照，
+// 只有最新的快照会被读取，旧快照仅用于排查与回溯。结果中 Written 为删除的文档数。
+func pruneSnapshots(ctxBg context.Context, svcCtx *svc.ServiceContext) (res RunResult) {
+	res = RunResult{Job: jobPrune, Start: time.Now()}
+	defer func() { res.End = time.Now() }()
+	defer recoverRun("pruneSnapshots", &res)
+	lock, ok := acquireTaskLock(ctxBg, svcCtx, jobPrune, 3*time.Second)
+	if !ok {
+		cronInfof("pruneSnapshots: acquire lock timeout, skip this run")
+		res.Skipped = true
+		return res
+	}
+	defer lock.release()
+	ctx := lock.ctx
+	cfg := svcCtx.Config.Retention
+	st := svcCtx.Store
+	jobs := []struct {
+		name  string
+		prune func(ctx context.Context, r store.Retention) (int64, error)
+		r     store.Retention
+	}{
+		{"spot summary_all", st.SpotSnapshots.Prune, retentionOf(cfg.SummaryAll)},
+		{"derivative summary_all", st.DerivativeSnapshots.Prune, retentionOf(cfg.SummaryAll)},
+		{"spot summary", st.SpotSummaries.Prune, retentionOf(cfg.Summary)},
+		{"derivative summary", st.DerivativeSummaries.Prune, retentionOf(cfg.Summary)},
+		{"spot config", st.SpotConfigs.Prune, retentionOf(cfg.Config)},
+		{"derivative config", st.DerivativeConfigs.Prune, retentionOf(cfg.Config)},
+	}
+	res.Units = len(jobs)
+	for _, job := range jobs {
+		if err := lock.check(ctx); err != nil {
+			res.fail("%s: %v", job.name, err)
+			return res
+		}
+		start := time.Now()
+		n, err := job.prune(ctx, job.r)
+		res.Written += int(n)
+		if err != nil {
+			res.fail("%s: %v", job.name, err)
+			cronErrorf("prune %s: deleted %d before error: %v", job.name, n, err)
+			continue
+		}
+		cronInfof("prune %s: deleted %d in %s", job.name, n, time.Since(start))
+	}
+	return res
+}
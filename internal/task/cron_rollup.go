@@ -0,0 +1,78 @@
+package task
+
+import (
+	"context"
+
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+// rollupBase 是 rollup 的源周期：只从上游拉取 1 分钟 K 线
+const rollupBase = "1"
+
+// verifyBars 是校验时从上游取回的已收盘 K 线数量
+const verifyBars = 3
+
+// historyResolutions 返回 spot/market 历史任务需要从上游拉取的周期。
+func historyResolutions(svcCtx *svc.ServiceContext) []string {
+	if svcCtx.Config.Cron.Rollup.Enabled {
+		return []string{rollupBase}
+	}
+	return svcCtx.Resolutions.Market(context.Background())
+}
+
+// upstreamBars 从上游拉取某个周期最新的 countback 根 K 线，仅用于校验。
+type upstreamBars func(ctx context.Context, resolution string, countback int) ([]store.Candle, error)
+
+// rollupCandles 用已存储的 1 分钟 K 线重算 market 自 since 起涉及的 resolutions 各周期 K 线并覆盖写入。
+// verify 非空时与上游对比最新的已收盘 K 线，只记录差异。
+func rollupCandles(ctx context.Context, repo store.CandleRepo, market string, since int64, resolutions []string, verify upstreamBars) {
+	for _, res := range resolutions {
+		if res == rollupBase {
+			continue
+		}
+		r, err := candle.ParseResolution(res)
+		if err != nil {
+			cronErrorf("rollup %s@%s: %v", market, res, err)
+			continue
+		}
+		// 与 Resample 相同的分桶：周线从周一开始，不能按纪元对齐（纪元是周四）
+		start := r.Bucket(since)
+		bars, err := repo.Find(ctx, store.CandleQuery{Market: market, Resolution: rollupBase, From: start})
+		if err != nil {
+			cronErrorf("rollup %s@%s: load 1m bars: %v", market, res, err)
+			continue
+		}
+		if len(bars) == 0 {
+			continue
+		}
+		derived := candle.Resample(bars, r, res)
+		// 1 分钟数据若从首个桶中间才开始，该桶不完整，保留原有数据
+		if bars[len(bars)-1].T != start {
+			before, err := repo.Find(ctx, store.CandleQuery{Market: market, Resolution: rollupBase, To: start - 1, Limit: 1})
+			if err == nil && len(before) == 0 {
+				derived = derived[1:]
+			}
+		}
+		if _, err := repo.Overwrite(ctx, derived); err != nil {
+			cronErrorf("rollup %s@%s: store: %v", market, res, err)
+			continue
+		}
+		if verify != nil && len(derived) > 1 {
+			verifyRollup(ctx, market, res, derived[:len(derived)-1], verify)
+		}
+	}
+}
+
+// verifyRollup 对比已收盘的推导 K 线与上游数据（最新一根仍在变化，不参与对比）。
+func verifyRollup(ctx context.Context, market, res string, closed []store.Candle, verify upstreamBars) {
+	upstream, err := verify(ctx, res, verifyBars+1)
+	if err != nil {
+		cronErrorf("rollup verify %s@%s: fetch upstream: %v", market, res, err)
+		return
+	}
+	if bad := candle.Mismatches(closed, upstream, 1e-6); len(bad) > 0 {
+		cronErrorf("rollup verify %s@%s: %d bars differ from upstream, t=%v", market, res, len(bad), bad)
+	}
+}
@@ -0,0 +1,36 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+)
+
+func TestRollupWeeklyBarSpanningSince(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewMemoryStore().SpotCandles
+	const monday = 1704067200 // 2024-01-01 00:00 UTC
+	const day = 86400
+	bars := []store.Candle{
+		{Market: "m1", Resolution: rollupBase, T: monday, O: 1, H: 1, L: 1, C: 1, V: 1},
+		{Market: "m1", Resolution: rollupBase, T: monday + 2*day, O: 2, H: 5, L: 2, C: 2, V: 1},
+		{Market: "m1", Resolution: rollupBase, T: monday + 4*day, O: 3, H: 3, L: 0.5, C: 3, V: 1},
+		{Market: "m1", Resolution: rollupBase, T: monday + 6*day, O: 4, H: 4, L: 4, C: 4, V: 1},
+	}
+	if _, err := repo.Upsert(ctx, bars); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+
+	// since 落在周五，按纪元对齐会从周四开始，只聚合到半周的数据
+	rollupCandles(ctx, repo, "m1", monday+4*day+3600, []string{"1W"}, nil)
+
+	got, err := repo.Find(ctx, store.CandleQuery{Market: "m1", Resolution: "1W"})
+	if err != nil || len(got) != 1 {
+		t.Fatalf("weekly bars = %#v, %v", got, err)
+	}
+	w := got[0]
+	if w.T != monday || w.O != 1 || w.H != 5 || w.L != 0.5 || w.C != 4 || w.V != 4 {
+		t.Fatalf("weekly bar = %#v, want the whole week from Monday", w)
+	}
+}
@@ -0,0 +1,31 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+// recordRun 将一次任务执行结果写入 job_runs，供 /api/admin/jobs 查询；写入失败只记录日志。
+func recordRun(ctx context.Context, svcCtx *svc.ServiceContext, res RunResult) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	err := svcCtx.Store.JobRuns.Insert(ctx, store.JobRun{
+		Job:        res.Job,
+		Start:      res.Start,
+		End:        res.End,
+		DurationMs: res.End.Sub(res.Start).Milliseconds(),
+		Units:      res.Units,
+		Fetched:    res.Fetched,
+		Written:    res.Written,
+		Failed:     res.Failed,
+		Skipped:    res.Skipped,
+		Manual:     res.Manual,
+		Errors:     res.Errors,
+	})
+	if err != nil {
+		cronErrorf("record %s run: %v", res.Job, err)
+	}
+}
@@ -0,0 +1,128 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+var (
+	// ErrUnknownJob is returned for a job name no job is registered under.
+	ErrUnknownJob = errors.New("task: unknown job")
+	// ErrNoTarget is returned when a Target is given for a job that does not
+	// work per market or resolution.
+	ErrNoTarget = errors.New("task: job does not take a market or resolution")
+)
+
+// Target narrows a manual run to some units; empty fields match everything.
+type Target struct {
+	Market     string
+	Resolution string
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("market=%q resolution=%q", t.Market, t.Resolution)
+}
+
+func (t Target) filter(units []unit) []unit {
+	if t == (Target{}) {
+		return units
+	}
+	out := units[:0:0]
+	for _, u := range units {
+		if (t.Market == "" || u.Market == t.Market) && (t.Resolution == "" || u.Resolution == t.Resolution) {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// targeted is implemented by jobs that can run for a Target only.
+type targeted interface {
+	RunTarget(ctx context.Context, svcCtx *svc.ServiceContext, t Target) RunResult
+}
+
+// funcJob adapts a maintenance function such as gap repair into a job.
+type funcJob struct {
+	name string
+	run  func(ctx context.Context) RunResult
+}
+
+func (f funcJob) Name() string { return f.name }
+
+func (f funcJob) Run(ctx context.Context, _ *svc.ServiceContext) RunResult { return f.run(ctx) }
+
+// allJobs returns every job of this service. Gap repair, snapshot pruning and
+// the market registry jobs are included even when disabled in config;
+// StartCron decides what to schedule.
+func allJobs(svcCtx *svc.ServiceContext, client *injective.Client) []job {
+	var jobs []job
+	jobs = append(jobs, spotJobs(svcCtx, client)...)
+	jobs = append(jobs, derivativeJobs(svcCtx, client)...)
+	jobs = append(jobs, marketJobs(svcCtx, client)...)
+	jobs = append(jobs,
+		funcJob{jobGapRepair, func(ctx context.Context) RunResult { return repairCandleGaps(ctx, svcCtx, client) }},
+		funcJob{jobPrune, func(ctx context.Context) RunResult { return pruneSnapshots(ctx, svcCtx) }},
+		funcJob{jobMarketSync, func(ctx context.Context) RunResult { return syncMarketRegistry(ctx, svcCtx) }},
+		funcJob{jobMarketOnboard, func(ctx context.Context) RunResult { return onboardMarkets(ctx, svcCtx, client) }},
+	)
+	return jobs
+}
+
+// JobNames lists the names of every job, in registration order.
+func JobNames(svcCtx *svc.ServiceContext) []string {
+	var names []string
+	for _, j := range allJobs(svcCtx, svcCtx.Injective) {
+		names = append(names, j.Name())
+	}
+	return names
+}
+
+// ManualRun prepares a manual run of the job called name, restricted to t.
+// The returned function runs it once and records the run; like a scheduled
+// run it takes the job's task lock and is skipped if another run holds it.
+// Paused jobs can still be run manually.
+func ManualRun(svcCtx *svc.ServiceContext, name string, t Target) (func(ctx context.Context) RunResult, error) {
+	client := svcCtx.Injective
+	for _, j := range allJobs(svcCtx, client) {
+		if j.Name() != name {
+			continue
+		}
+		run := func(ctx context.Context) RunResult { return j.Run(ctx, svcCtx) }
+		if t != (Target{}) {
+			tj, ok := j.(targeted)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrNoTarget, name)
+			}
+			run = func(ctx context.Context) RunResult { return tj.RunTarget(ctx, svcCtx, t) }
+		}
+		return func(ctx context.Context) RunResult {
+			cronInfof("manual run %s %s", name, t)
+			res := run(ctx)
+			res.Manual = true
+			recordRun(ctx, svcCtx, res)
+			return res
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownJob, name)
+}
+
+// SetPaused pauses or resumes the job called name on every replica. A paused
+// job keeps its schedule but skips its runs until resumed.
+func SetPaused(ctx context.Context, svcCtx *svc.ServiceContext, name string, paused bool) error {
+	known := false
+	for _, n := range JobNames(svcCtx) {
+		known = known || n == name
+	}
+	if !known {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	if err := svcCtx.Store.JobStates.SetPaused(ctx, name, paused); err != nil {
+		return err
+	}
+	cronInfof("job %s paused=%v", name, paused)
+	return nil
+}
@@ -0,0 +1,215 @@
+package task
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/zeromicro/go-zero/core/logx"
+
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+// errLockLost is returned by taskLock.check once the lease could not be renewed.
+var errLockLost = errors.New("task lock lost")
+
+// 仅当锁仍属于自己（value == token）时才续期 / 删除，避免误删其他副本的锁
+var (
+	extendTaskLockScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+    return redis.call('pexpire', KEYS[1], ARGV[2])
+else
+    return 0
+end`)
+	releaseTaskLockScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+    return redis.call('del', KEYS[1])
+else
+    return 0
+end`)
+)
+
+// heldLocks 记录本进程当前持有的任务锁（lockKey -> token），关闭时由 ReleaseTaskLocks 兜底释放。
+var heldLocks sync.Map
+
+// taskLock is a held distributed task lock. Its lease is renewed in the
+// background until release; ctx is cancelled when the lease is lost, so the
+// job stops instead of running alongside the next holder.
+type taskLock struct {
+	svcCtx *svc.ServiceContext
+	name   string
+	key    string
+	token  string
+	// fence increases with every acquisition of the lock. It is carried in ctx
+	// (store.WithFence) so that candle and snapshot writes of a holder whose
+	// lease expired are refused by the write itself once a newer holder wrote.
+	fence int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	lost   atomic.Bool // the lease was lost while the job ran
+	done   chan struct{}
+	once   sync.Once
+}
+
+// acquireTaskLock 尝试在 wait 时间内基于 Redis 获取分布式任务锁 lock:task:<name>。
+// 锁的值为随机 token，持有期间每 TTL/3 续期一次；同时 INCR lock:task:<name>:fence 得到 fencing token。
+// 成功返回锁与 true，调用方应在 lock.ctx 下执行并在结束时调用 release；失败返回 nil、false。
+// 未配置 Redis（测试、本地单实例）时不加锁，fence 为 0 表示不做 fencing 检查。
+func acquireTaskLock(ctx context.Context, svcCtx *svc.ServiceContext, name string, wait time.Duration) (*taskLock, bool) {
+	if name == "" {
+		return nil, false
+	}
+	l := &taskLock{svcCtx: svcCtx, name: name, key: "lock:task:" + name, done: make(chan struct{})}
+	l.ctx, l.cancel = context.WithCancel(ctx)
+	if svcCtx.Redis == nil {
+		return l, true
+	}
+	token, err := newLockToken()
+	if err != nil {
+		logx.Errorf("acquireTaskLock token error for key=%s: %v", name, err)
+		l.cancel()
+		return nil, false
+	}
+	l.token = token
+
+	// TTL 与重试间隔从配置读取，提供合理默认
+	lockTTL := time.Duration(svcCtx.Config.Redis.LockTTLSeconds) * time.Second
+	if lockTTL <= 0 {
+		lockTTL = 60 * time.Second
+	}
+	retryMs := svcCtx.Config.Redis.RetryMs
+	if retryMs <= 0 {
+		retryMs = 100
+	}
+	retryInterval := time.Duration(retryMs) * time.Millisecond
+
+	// 获取带超时的上下文
+	lockCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	for {
+		ok, err := svcCtx.Redis.SetNX(lockCtx, l.key, token, lockTTL).Result()
+		if err != nil && lockCtx.Err() == nil {
+			logx.Errorf("acquireTaskLock SetNX error for key=%s: %v", name, err)
+			l.cancel()
+			return nil, false
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-lockCtx.Done():
+			logx.Infof("acquireTaskLock timeout for key=%s", name)
+			l.cancel()
+			return nil, false
+		case <-time.After(retryInterval):
+		}
+	}
+	heldLocks.Store(l.key, token)
+	if l.fence, err = svcCtx.Redis.Incr(ctx, l.key+":fence").Result(); err != nil {
+		logx.Errorf("acquireTaskLock fence error for key=%s: %v", name, err)
+		l.release()
+		return nil, false
+	}
+	l.ctx = store.WithFence(l.ctx, name, l.fence)
+	go l.renew(lockTTL)
+	return l, true
+}
+
+// renew extends the lease every ttl/3. When the lock is taken over, or cannot
+// be renewed before it would expire, the job context is cancelled.
+func (l *taskLock) renew(ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+		n, err := extendTaskLockScript.Run(ctx, l.svcCtx.Redis, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+		cancel()
+		switch {
+		case err == nil && n == 1:
+			renewed = time.Now()
+			continue
+		case err == nil:
+			cronErrorf("task lock %s lost to another holder, stop the run", l.name)
+		case time.Since(renewed) < ttl:
+			logx.Errorf("renewTaskLock error for key=%s: %v", l.name, err)
+			continue
+		default:
+			cronErrorf("task lock %s expired, renewal failed: %v", l.name, err)
+		}
+		l.lost.Store(true)
+		l.cancel()
+		return
+	}
+}
+
+// check is called before the job writes: it fails once the lease is lost or a
+// newer holder of the lock has written. Candle and snapshot writes are fenced
+// again by the store; check covers the repos that are not (registry,
+// checkpoints) and stops a stale holder before it fetches more.
+func (l *taskLock) check(ctx context.Context) error {
+	if l.lost.Load() {
+		return fmt.Errorf("%w: %s", errLockLost, l.name)
+	}
+	if l.fence == 0 {
+		return nil
+	}
+	return l.svcCtx.Store.Fences.Check(ctx, l.name, l.fence)
+}
+
+// release stops renewal and deletes the lock if it still holds our token.
+func (l *taskLock) release() {
+	l.once.Do(func() {
+		close(l.done)
+		l.cancel()
+		if l.svcCtx.Redis == nil {
+			return
+		}
+		heldLocks.Delete(l.key)
+		releaseLockKey(l.svcCtx.Redis, l.key, l.token)
+	})
+}
+
+func releaseLockKey(rdb *redis.Client, key, token string) {
+	// 使用独立的短超时后台上下文，避免受任务上下文取消影响
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := releaseTaskLockScript.Run(ctx, rdb, []string{key}, token).Err(); err != nil {
+		logx.Errorf("releaseTaskLock error for key=%s: %v", key, err)
+	}
+}
+
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ReleaseTaskLocks 释放本进程仍持有的任务锁。仅在关闭时等待任务超时后调用，
+// 避免被中断的任务把锁一直占到 TTL 过期；只删除 token 仍属于本进程的锁。
+func ReleaseTaskLocks(svcCtx *svc.ServiceContext) {
+	heldLocks.Range(func(k, v any) bool {
+		heldLocks.Delete(k)
+		if svcCtx.Redis != nil {
+			releaseLockKey(svcCtx.Redis, k.(string), v.(string))
+			cronInfof("released task lock %s on shutdown", k)
+		}
+		return true
+	})
+}
@@ -0,0 +1,248 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/metric"
+
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+var marketEvents = metric.NewCounterVec(&metric.CounterVecOpts{
+	Namespace: "chronos",
+	Subsystem: "markets",
+	Name:      "events_total",
+	Help:      "markets added to or removed from the registry.",
+	Labels:    []string{"market_type", "event"},
+})
+
+// registryTypes 是注册表跟踪的市场类型，market id 来自各自的 summary_all 快照。
+var registryTypes = []consts.MarketType{consts.MarketTypeSpot, consts.MarketTypeDerivative}
+
+// registeredMarkets 返回注册表中仍在上架的 market；注册表关闭或尚未同步时回退到最新的 summary_all 快照。
+func registeredMarkets(svcCtx *svc.ServiceContext, resolution string, marketType consts.MarketType) []string {
+	if svcCtx.Config.Cron.Markets.Enabled {
+		markets, err := svcCtx.Store.Markets.List(context.Background(), string(marketType), true)
+		if err != nil {
+			cronErrorf("list %s market registry: %v", marketType, err)
+		}
+		if len(markets) > 0 {
+			ids := make([]string, 0, len(markets))
+			for _, m := range markets {
+				ids = append(ids, m.Market)
+			}
+			return ids
+		}
+	}
+	return getMarketSummaryAllIds(svcCtx, resolution, marketType)
+}
+
+// syncMarketRegistry 对比最新的 24h summary_all 快照与注册表：新出现的 market 登记为待补历史，
+// 连续 RemoveAfterMin 未出现的 market 标记为下架。结果中 Written 为产生的事件数。
+func syncMarketRegistry(ctxBg context.Context, svcCtx *svc.ServiceContext) (res RunResult) {
+	res = RunResult{Job: jobMarketSync, Start: time.Now()}
+	defer func() { res.End = time.Now() }()
+	defer recoverRun("syncMarketRegistry", &res)
+	lock, ok := acquireTaskLock(ctxBg, svcCtx, jobMarketSync, 3*time.Second)
+	if !ok {
+		cronInfof("syncMarketRegistry: acquire lock timeout, skip this run")
+		res.Skipped = true
+		return res
+	}
+	defer lock.release()
+	ctx := lock.ctx
+	for _, typ := range registryTypes {
+		res.Units++
+		// 快照缺失时不能据此判断下架，跳过本次同步
+		ids := getMarketSummaryAllIds(svcCtx, "24h", typ)
+		if len(ids) == 0 {
+			res.fail("%s: no summary_all snapshot", typ)
+			continue
+		}
+		res.Fetched++
+		if err := lock.check(ctx); err != nil {
+			res.fail("%s: %v", typ, err)
+			return res
+		}
+		n, err := syncMarkets(ctx, svcCtx, typ, ids, time.Now())
+		res.Written += n
+		if err != nil {
+			res.fail("%s: %v", typ, err)
+			cronErrorf("sync %s market registry: %v", typ, err)
+		}
+	}
+	return res
+}
+
+// syncMarkets records the markets of one type listed in the current snapshot
+// and returns the number of events emitted. The first sync of a type only
+// registers what is listed, without events or backfills: those markets are
+// already being ingested.
+func syncMarkets(ctx context.Context, svcCtx *svc.ServiceContext, typ consts.MarketType, current []string, now time.Time) (int, error) {
+	repo := svcCtx.Store.Markets
+	cfg := svcCtx.Config.Cron.Markets
+	registered, err := repo.List(ctx, string(typ), false)
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[string]store.Market, len(registered))
+	for _, m := range registered {
+		known[m.Market] = m
+	}
+	listed := make(map[string]bool, len(current))
+	events := 0
+	for _, id := range current {
+		listed[id] = true
+		m, ok := known[id]
+		if ok && m.Active {
+			continue
+		}
+		if !ok {
+			m = store.Market{MarketType: string(typ), Market: id, FirstSeen: now}
+		}
+		m.Active, m.LastSeen, m.RemovedAt = true, now, nil
+		if len(registered) == 0 {
+			m.Onboarded = true
+			if err := repo.Save(ctx, m); err != nil {
+				return events, err
+			}
+			continue
+		}
+		// 新上架（或重新上架）的 market 需要补全历史，区间固定下来以便中断后断点续传
+		m.Onboarded = false
+		m.OnboardFrom = now.AddDate(0, 0, -cfg.BackfillDays).Unix()
+		m.OnboardTo = now.Unix()
+		if err := repo.Save(ctx, m); err != nil {
+			return events, err
+		}
+		if err := emitMarketEvent(ctx, svcCtx, m, store.MarketAdded, now); err != nil {
+			return events, err
+		}
+		events++
+	}
+	if len(registered) == 0 {
+		cronInfof("market registry: registered %d %s markets", len(current), typ)
+	}
+	if err := repo.Seen(ctx, string(typ), current, now); err != nil {
+		return events, err
+	}
+	removeAfter := time.Duration(cfg.RemoveAfterMin) * time.Minute
+	for _, m := range registered {
+		if !m.Active || listed[m.Market] || now.Sub(m.LastSeen) < removeAfter {
+			continue
+		}
+		m.Active, m.RemovedAt = false, &now
+		if err := repo.Save(ctx, m); err != nil {
+			return events, err
+		}
+		if err := emitMarketEvent(ctx, svcCtx, m, store.MarketRemoved, now); err != nil {
+			return events, err
+		}
+		events++
+	}
+	return events, nil
+}
+
+// emitMarketEvent 记录一次上架/下架事件：写入 market_events、打日志并计数。
+func emitMarketEvent(ctx context.Context, svcCtx *svc.ServiceContext, m store.Market, event string, at time.Time) error {
+	cronInfof("market registry: %s market %s %s", m.MarketType, m.Market, event)
+	marketEvents.Inc(m.MarketType, event)
+	return svcCtx.Store.Markets.AddEvent(ctx, store.MarketEvent{MarketType: m.MarketType, Market: m.Market, Event: event, At: at})
+}
+
+// onboardMarkets 为注册表中尚未补全历史的新 market 回填 [OnboardFrom, OnboardTo] 的 K 线，
+// 完成后标记为已接入。回填基于 checkpoint，被中断后下次运行继续。
+// 结果中 Units 为待接入的 market 数，Fetched 为完成的数量，Written 为插入的 bars 数。
+func onboardMarkets(ctxBg context.Context, svcCtx *svc.ServiceContext, client *injective.Client) (res RunResult) {
+	res = RunResult{Job: jobMarketOnboard, Start: time.Now()}
+	defer func() { res.End = time.Now() }()
+	defer recoverRun("onboardMarkets", &res)
+	lock, ok := acquireTaskLock(ctxBg, svcCtx, jobMarketOnboard, 3*time.Second)
+	if !ok {
+		cronInfof("onboardMarkets: acquire lock timeout, skip this run")
+		res.Skipped = true
+		return res
+	}
+	defer lock.release()
+	ctx := lock.ctx
+	for _, typ := range registryTypes {
+		markets, err := svcCtx.Store.Markets.List(ctx, string(typ), true)
+		if err != nil {
+			res.fail("%s: %v", typ, err)
+			continue
+		}
+		for _, m := range markets {
+			if m.Onboarded {
+				continue
+			}
+			res.Units++
+			if err := lock.check(ctx); err != nil {
+				res.fail("%s: %v", typ, err)
+				return res
+			}
+			n, err := onboardMarket(ctx, svcCtx, client, m)
+			res.Written += n
+			if err == nil {
+				err = svcCtx.Store.Markets.SetOnboarded(ctx, m.MarketType, m.Market)
+			}
+			if err != nil {
+				res.fail("%s %s: %v", typ, m.Market, err)
+				cronErrorf("onboard %s market %s: %v", typ, m.Market, err)
+				if ctx.Err() != nil {
+					return res
+				}
+				continue
+			}
+			res.Fetched++
+			cronInfof("onboard %s market %s: backfilled %d bars", typ, m.Market, n)
+		}
+	}
+	return res
+}
+
+// onboardMarket backfills the history of one new market: a spot market into
+// the spot and market collections, a derivative market into the market
+// collection. Derivative history is keyed by symbol and its first fetch by
+// derivative_history_fetch already covers the full history.
+func onboardMarket(ctx context.Context, svcCtx *svc.ServiceContext, client *injective.Client, m store.Market) (int, error) {
+	types := []consts.MarketType{consts.MarketTypeMarket}
+	if m.MarketType == string(consts.MarketTypeSpot) {
+		types = append(types, consts.MarketTypeSpot)
+	}
+	total := 0
+	for _, typ := range types {
+		if typ == consts.MarketTypeMarket && svcCtx.Store.MarketCandles == nil {
+			continue
+		}
+		for _, res := range historyResolutions(svcCtx) {
+			job := BackfillJob{Type: typ, Markets: []string{m.Market}, Resolutions: []string{res}, From: m.OnboardFrom, To: m.OnboardTo}
+			if typ == consts.MarketTypeMarket {
+				// market/history 只能按 countback 拉取且有根数上限：只回填上游还能返回的部分，
+				// 一页覆盖整个区间，避免每页都从当前时间重拉
+				job.From = max(job.From, marketEarliest(res, time.Now().Unix()))
+				if job.From > job.To {
+					continue
+				}
+				job.PageBars = int((job.To-job.From)/candle.Seconds(res)) + 1
+			}
+			n, err := Backfill(ctx, svcCtx, client, job)
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+		if svcCtx.Config.Cron.Rollup.Enabled {
+			repo := svcCtx.Store.SpotCandles
+			if typ == consts.MarketTypeMarket {
+				repo = svcCtx.Store.MarketCandles
+			}
+			rollupCandles(ctx, repo, m.Market, m.OnboardFrom, svcCtx.Resolutions.Market(ctx), nil)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,54 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+func TestSyncMarketsOnboardsNewAndRemovesAfterGrace(t *testing.T) {
+	ctx := context.Background()
+	var c config.Config
+	c.Cron.Markets = config.MarketRegistryConf{Enabled: true, RemoveAfterMin: 30, BackfillDays: 10}
+	svcCtx := &svc.ServiceContext{Config: c, Store: store.NewMemoryStore()}
+	repo := svcCtx.Store.Markets
+	spot := consts.MarketTypeSpot
+	t0 := time.Unix(1_700_000_000, 0)
+
+	// 首次同步只登记，不产生事件也不回填
+	if n, err := syncMarkets(ctx, svcCtx, spot, []string{"a", "b"}, t0); err != nil || n != 0 {
+		t.Fatalf("first sync: events=%d err=%v", n, err)
+	}
+	n, err := syncMarkets(ctx, svcCtx, spot, []string{"a", "c"}, t0.Add(time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("second sync: events=%d err=%v, want 1 (c added, b within grace)", n, err)
+	}
+	markets, _ := repo.List(ctx, string(spot), true)
+	if len(markets) != 3 || markets[2].Market != "c" || markets[2].Onboarded || !markets[0].Onboarded {
+		t.Fatalf("markets = %+v", markets)
+	}
+	if c := markets[2]; c.OnboardTo != t0.Add(time.Minute).Unix() || c.OnboardTo-c.OnboardFrom != 10*86400 {
+		t.Fatalf("onboard range = %d-%d", c.OnboardFrom, c.OnboardTo)
+	}
+
+	if n, _ := syncMarkets(ctx, svcCtx, spot, []string{"a", "c"}, t0.Add(31*time.Minute)); n != 1 {
+		t.Fatalf("third sync: events=%d, want 1 (b removed)", n)
+	}
+	active, _ := repo.List(ctx, string(spot), true)
+	all, _ := repo.List(ctx, string(spot), false)
+	if len(active) != 2 || len(all) != 3 || all[1].Active || all[1].RemovedAt == nil {
+		t.Fatalf("active=%+v all=%+v", active, all)
+	}
+	events, _ := repo.Events(ctx, "", 10)
+	if len(events) != 2 || events[0].Market != "b" || events[0].Event != store.MarketRemoved || events[1].Event != store.MarketAdded {
+		t.Fatalf("events = %+v", events)
+	}
+	if ids := registeredMarkets(svcCtx, "24h", spot); len(ids) != 2 || ids[0] != "a" || ids[1] != "c" {
+		t.Fatalf("registeredMarkets = %v", ids)
+	}
+}
@@ -0,0 +1,305 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/metric"
+
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/injective"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+var (
+	pipelineRuns = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: "chronos",
+		Subsystem: "pipeline",
+		Name:      "runs_total",
+		Help:      "ingestion pipeline runs by outcome (ok, failed, skipped).",
+		Labels:    []string{"job", "outcome"},
+	})
+	pipelineItems = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: "chronos",
+		Subsystem: "pipeline",
+		Name:      "items_total",
+		Help:      "units fetched, items written and units failed per ingestion pipeline.",
+		Labels:    []string{"job", "stage"},
+	})
+	pipelineDuration = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: "chronos",
+		Subsystem: "pipeline",
+		Name:      "duration_ms",
+		Help:      "ingestion pipeline run duration in milliseconds.",
+		Labels:    []string{"job"},
+		Buckets:   []float64{50, 250, 1000, 5000, 15000, 60000, 300000},
+	})
+)
+
+// unit is one fetch of a pipeline, e.g. the summary of one market at one
+// resolution. Fields a data kind does not use stay empty.
+type unit struct {
+	Resolution string
+	Market     string
+}
+
+func (u unit) String() string {
+	switch {
+	case u.Market != "" && u.Resolution != "":
+		return u.Market + "@" + u.Resolution
+	case u.Market != "":
+		return u.Market
+	}
+	return u.Resolution
+}
+
+// RunResult is the outcome of one pipeline run.
+type RunResult struct {
+	Job     string
+	Start   time.Time
+	End     time.Time
+	Units   int      // units planned
+	Fetched int      // units fetched from Injective
+	Written int      // items stored, as counted by the store stage
+	Failed  int      // units that failed in any stage
+	Skipped bool     // another replica held the job lock
+	Manual  bool     // triggered through the admin API or CLI
+	Errors  []string // first maxRunErrors error messages
+}
+
+const maxRunErrors = 10
+
+// fail counts one failure and keeps its message if there is room.
+func (r *RunResult) fail(format string, args ...any) {
+	r.Failed++
+	if len(r.Errors) < maxRunErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// recoverRun turns a panic of a job run into a failure of res; use it as
+// `defer recoverRun(name, &res)`.
+func recoverRun(name string, res *RunResult) {
+	if r := recover(); r != nil {
+		res.fail("panic: %v", r)
+		cronErrorf("panic recovered at %s: %v\nstack:\n%s", name, r, debug.Stack())
+	}
+}
+
+// job is a runnable ingestion pipeline, independent of its data types.
+type job interface {
+	Name() string
+	Run(ctx context.Context, svcCtx *svc.ServiceContext) RunResult
+}
+
+// Pipeline ingests one data kind of one market type in three stages: Fetch
+// from Injective, Transform into what the store keeps, and Store. Units lists
+// what to fetch on each run. Every pipeline gets the same job lock, fetch
+// retries, bounded concurrency, metrics and run logging. Each Store is
+// preceded by a fencing check of the job lock (see taskLock.check).
+type Pipeline[In, Out any] struct {
+	// JobName is the job and lock name, e.g. "spot_config_fetch".
+	JobName    string
+	MarketType consts.MarketType
+	Kind       string // store.Kind*
+
+	Units     func(ctx context.Context) ([]unit, error)
+	Fetch     func(ctx context.Context, u unit) (In, error)
+	Transform func(u unit, in In) (Out, error)
+	// Store persists out and returns how many items it wrote.
+	Store func(ctx context.Context, u unit, out Out) (int, error)
+
+	Workers int           // concurrent units, default 1
+	Retries *int          // extra fetch attempts after a failure; nil uses Cron.FetchRetries
+	Timeout time.Duration // per-unit timeout, default 30s
+}
+
+func (p *Pipeline[In, Out]) Name() string { return p.JobName }
+
+// Run executes one pass over Units. It never panics; a unit that panics counts
+// as failed.
+func (p *Pipeline[In, Out]) Run(ctx context.Context, svcCtx *svc.ServiceContext) RunResult {
+	return p.RunTarget(ctx, svcCtx, Target{})
+}
+
+// RunTarget is Run restricted to the units matching t.
+func (p *Pipeline[In, Out]) RunTarget(ctx context.Context, svcCtx *svc.ServiceContext, t Target) (res RunResult) {
+	res = RunResult{Job: p.JobName, Start: time.Now()}
+	defer func() {
+		res.End = time.Now()
+		p.report(res)
+	}()
+	defer recoverRun("pipeline:"+p.JobName, &res)
+	lock, ok := acquireTaskLock(ctx, svcCtx, p.JobName, 3*time.Second)
+	if !ok {
+		res.Skipped = true
+		return res
+	}
+	defer lock.release()
+	ctx = lock.ctx
+
+	units := []unit{{}}
+	if p.Units != nil {
+		var err error
+		if units, err = p.Units(ctx); err != nil {
+			res.fail("list units: %v", err)
+			return res
+		}
+	}
+	if units = t.filter(units); len(units) == 0 {
+		res.fail("no unit matches %s", t)
+		return res
+	}
+	res.Units = len(units)
+
+	retries := svcCtx.Config.Cron.FetchRetries
+	if p.Retries != nil {
+		retries = *p.Retries
+	}
+	var mu sync.Mutex
+	sem := make(chan struct{}, max(p.Workers, 1))
+	var wg sync.WaitGroup
+	for i, u := range units {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			// 关闭中：不再派发剩余的 units
+			<-sem
+			mu.Lock()
+			res.fail("stopped after %d of %d units: %v", i, len(units), ctx.Err())
+			mu.Unlock()
+			break
+		}
+		wg.Add(1)
+		go func(u unit) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fetched, written, err := p.runUnit(ctx, lock, u, retries)
+			mu.Lock()
+			defer mu.Unlock()
+			if fetched {
+				res.Fetched++
+			}
+			res.Written += written
+			if err != nil {
+				res.fail("%s: %v", u, err)
+				cronErrorf("%s %s: %v", p.JobName, u, err)
+			}
+		}(u)
+	}
+	wg.Wait()
+	return res
+}
+
+func (p *Pipeline[In, Out]) runUnit(ctx context.Context, lock *taskLock, u unit, retries int) (fetched bool, written int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var in In
+	for attempt := 0; ; attempt++ {
+		if in, err = p.Fetch(ctx, u); err == nil {
+			break
+		}
+		// 熔断打开时重试无意义，留给下一轮
+		if attempt >= retries || ctx.Err() != nil || errors.Is(err, injective.ErrCircuitOpen) {
+			return false, 0, fmt.Errorf("fetch: %w", err)
+		}
+		select {
+		case <-time.After(time.Duration(attempt+1) * 500 * time.Millisecond):
+		case <-ctx.Done():
+			return false, 0, fmt.Errorf("fetch: %w", err)
+		}
+	}
+	out, err := p.Transform(u, in)
+	if err != nil {
+		return true, 0, fmt.Errorf("transform: %w", err)
+	}
+	if err := lock.check(ctx); err != nil {
+		return true, 0, fmt.Errorf("store: %w", err)
+	}
+	written, err = p.Store(ctx, u, out)
+	if err != nil {
+		return true, written, fmt.Errorf("store: %w", err)
+	}
+	return true, written, nil
+}
+
+func (p *Pipeline[In, Out]) report(res RunResult) {
+	outcome := "ok"
+	switch {
+	case res.Skipped:
+		outcome = "skipped"
+		cronInfof("%s: acquire lock timeout, skip this run", p.JobName)
+	case res.Failed > 0:
+		outcome = "failed"
+	}
+	pipelineRuns.Inc(p.JobName, outcome)
+	if res.Skipped {
+		return
+	}
+	pipelineItems.Add(float64(res.Fetched), p.JobName, "fetched")
+	pipelineItems.Add(float64(res.Written), p.JobName, "written")
+	pipelineItems.Add(float64(res.Failed), p.JobName, "failed")
+	pipelineDuration.Observe(res.End.Sub(res.Start).Milliseconds(), p.JobName)
+	cronInfof("%s: units %d fetched %d written %d failed %d in %s",
+		p.JobName, res.Units, res.Fetched, res.Written, res.Failed, res.End.Sub(res.Start))
+}
+
+// identity is the Transform of pipelines that store what they fetch.
+func identity[T any](_ unit, in T) (T, error) { return in, nil }
+
+// resolutionUnits lists one unit per resolution.
+func resolutionUnits(resolutions []string) func(ctx context.Context) ([]unit, error) {
+	return func(context.Context) ([]unit, error) {
+		units := make([]unit, 0, len(resolutions))
+		for _, res := range resolutions {
+			units = append(units, unit{Resolution: res})
+		}
+		return units, nil
+	}
+}
+
+// marketUnits lists one unit per (resolution, market); markets returns the
+// markets of a resolution.
+func marketUnits(resolutions func() []string, markets func(ctx context.Context, res string) ([]string, error)) func(ctx context.Context) ([]unit, error) {
+	return func(ctx context.Context) ([]unit, error) {
+		var units []unit
+		for _, res := range resolutions() {
+			ids, err := markets(ctx, res)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				units = append(units, unit{Resolution: res, Market: id})
+			}
+		}
+		return units, nil
+	}
+}
+
+// symbolUnits lists one unit per symbol returned by symbols.
+func symbolUnits(symbols func(ctx context.Context) ([]string, error)) func(ctx context.Context) ([]unit, error) {
+	return func(ctx context.Context) ([]unit, error) {
+		names, err := symbols(ctx)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]unit, 0, len(names))
+		for _, s := range names {
+			units = append(units, unit{Market: s})
+		}
+		return units, nil
+	}
+}
@@ -0,0 +1,177 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/candle"
+	"github.com/biya-coin/injective-chronos-go/internal/consts"
+	"github.com/biya-coin/injective-chronos-go/internal/store"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+// 各数据类型的 pipeline 构造函数：spot/derivative 只需提供对应的 client 方法与仓储。
+
+// configPipeline 拉取 chart config，内容变化时才写入新版本。
+func configPipeline[T any](typ consts.MarketType, fetch func(ctx context.Context) (*T, error), repo store.ConfigRepo[T]) job {
+	return &Pipeline[*T, T]{
+		JobName:    string(typ) + "_config_fetch",
+		MarketType: typ,
+		Kind:       store.KindConfig,
+		Fetch:      func(ctx context.Context, _ unit) (*T, error) { return fetch(ctx) },
+		Transform:  func(_ unit, in *T) (T, error) { return *in, nil },
+		Store: func(ctx context.Context, _ unit, cfg T) (int, error) {
+			changed, err := repo.Save(ctx, cfg)
+			if err != nil || !changed {
+				return 0, err
+			}
+			cronInfof("%s config changed, stored new version", typ)
+			return 1, nil
+		},
+	}
+}
+
+// summaryAllPipeline 按 resolution 拉取 summary_all 快照，周期见 Resolutions.Summary。
+func summaryAllPipeline[T any](svcCtx *svc.ServiceContext, typ consts.MarketType, fetch func(ctx context.Context, resolution string) ([]T, error), repo store.SnapshotRepo[T]) job {
+	return &Pipeline[[]T, []T]{
+		JobName:    string(typ) + "_summary_all_fetch",
+		MarketType: typ,
+		Kind:       store.KindSummaryAll,
+		Units: func(ctx context.Context) ([]unit, error) {
+			return resolutionUnits(svcCtx.Resolutions.Summary())(ctx)
+		},
+		Fetch:     func(ctx context.Context, u unit) ([]T, error) { return fetch(ctx, u.Resolution) },
+		Transform: identity[[]T],
+		Store: func(ctx context.Context, u unit, rows []T) (int, error) {
+			changed, err := repo.Save(ctx, u.Resolution, rows)
+			if err != nil || !changed {
+				return 0, err
+			}
+			return 1, nil
+		},
+	}
+}
+
+// summariesPipeline 按 (resolution, market) 拉取单个市场的 summary，market 来自同周期的 summary_all 快照。
+func summariesPipeline[T any](svcCtx *svc.ServiceContext, typ consts.MarketType, fetch func(ctx context.Context, market, resolution string) (*T, error), repo store.SummaryRepo[T]) job {
+	return &Pipeline[*T, T]{
+		JobName:    string(typ) + "_summaries_fetch",
+		MarketType: typ,
+		Kind:       store.KindSummary,
+		Units: marketUnits(svcCtx.Resolutions.Summary, func(_ context.Context, res string) ([]string, error) {
+			return getMarketSummaryAllIds(svcCtx, res, typ), nil
+		}),
+		Fetch:     func(ctx context.Context, u unit) (*T, error) { return fetch(ctx, u.Market, u.Resolution) },
+		Transform: func(_ unit, in *T) (T, error) { return *in, nil },
+		Store: func(ctx context.Context, u unit, one T) (int, error) {
+			return 1, repo.Insert(ctx, u.Market, u.Resolution, one)
+		},
+		Workers: 8,
+	}
+}
+
+// symbolInfo is one symbol of a columnar symbol_info response.
+type symbolInfo[I any] struct {
+	Symbol string
+	Info   I
+}
+
+// symbolInfoPipeline 拉取 symbol_info（按列返回），拆成每个 symbol 一条，只有内容变化时才写入新版本。
+func symbolInfoPipeline[In, I, S any](typ consts.MarketType, fetch func(ctx context.Context, group string) (*In, error), rows func(in *In) []symbolInfo[I], repo store.SymbolRepo[I, S]) job {
+	// 只同步不带 group 的 symbol_info（上游返回全部 symbol），存为 group=""；
+	// 接口按请求的 group 查询，其它 group 没有数据。
+	const group = ""
+	return &Pipeline[*In, []symbolInfo[I]]{
+		JobName:    string(typ) + "_symbol_info_fetch",
+		MarketType: typ,
+		Kind:       store.KindSymbolInfo,
+		Fetch:      func(ctx context.Context, _ unit) (*In, error) { return fetch(ctx, group) },
+		Transform:  func(_ unit, in *In) ([]symbolInfo[I], error) { return rows(in), nil },
+		Store: func(ctx context.Context, _ unit, infos []symbolInfo[I]) (int, error) {
+			written := 0
+			for _, row := range infos {
+				changed, err := repo.SaveInfo(ctx, row.Symbol, group, row.Info)
+				if err != nil {
+					cronErrorf("save %s symbol info -> symbol:%s: %v", typ, row.Symbol, err)
+					continue
+				}
+				if changed {
+					written++
+				}
+			}
+			return written, nil
+		},
+	}
+}
+
+// symbolsPipeline 为每个已有 symbol_info 的 symbol 拉取 symbols 文档，只有内容变化时才写入新版本，
+// 上游新增的周期（supported_resolutions）因此会被 resolutions 解析到。
+func symbolsPipeline[I, S any](typ consts.MarketType, fetch func(ctx context.Context, symbol string) (*S, error), repo store.SymbolRepo[I, S]) job {
+	return &Pipeline[*S, S]{
+		JobName:    string(typ) + "_symbols_fetch",
+		MarketType: typ,
+		Kind:       store.KindSymbols,
+		Units: symbolUnits(func(ctx context.Context) ([]string, error) {
+			return repo.InfoSymbols(ctx, "")
+		}),
+		Fetch:     func(ctx context.Context, u unit) (*S, error) { return fetch(ctx, u.Market) },
+		Transform: func(_ unit, in *S) (S, error) { return *in, nil },
+		Store: func(ctx context.Context, u unit, s S) (int, error) {
+			changed, err := repo.SaveSymbols(ctx, u.Market, s)
+			if err != nil || !changed {
+				return 0, err
+			}
+			return 1, nil
+		},
+	}
+}
+
+// historySource describes how one market type fetches K 线 history.
+type historySource[In any] struct {
+	// fetch requests the bars after the series watermark lastT; ok is false when
+	// the series has no data yet.
+	fetch func(ctx context.Context, u unit, lastT int64, ok bool) (In, error)
+	// candles converts the response of unit u.
+	candles func(u unit, in In) []store.Candle
+	// verify returns the upstream bars used to verify rollups of a market; nil
+	// when the market type has no rollup.
+	verify func(market string) upstreamBars
+}
+
+// historyPipeline 按 (resolution, market) 增量拉取 K 线：从序列自己的 watermark 起拉取，最新 LiveBars
+// 根覆盖写入，成功后推进 sync_state；开启 rollup 时由 1 分钟 K 线重算其余周期。
+func historyPipeline[In any](svcCtx *svc.ServiceContext, typ consts.MarketType, name string, repo store.CandleRepo, units func(ctx context.Context) ([]unit, error), src historySource[In]) job {
+	return &Pipeline[In, []store.Candle]{
+		JobName:    name,
+		MarketType: typ,
+		Kind:       store.KindHistory,
+		Units:      units,
+		Fetch: func(ctx context.Context, u unit) (In, error) {
+			lastT, ok := seriesWatermark(ctx, svcCtx, typ, repo, u.Market, u.Resolution)
+			return src.fetch(ctx, u, lastT, ok)
+		},
+		Transform: func(u unit, in In) ([]store.Candle, error) { return src.candles(u, in), nil },
+		Store: func(ctx context.Context, u unit, bars []store.Candle) (int, error) {
+			written, err := storeCandles(ctx, repo, bars, svcCtx.Config.Cron.LiveBars)
+			if err != nil {
+				return written, err
+			}
+			advanceWatermark(ctx, svcCtx, typ, u.Market, u.Resolution, bars)
+			rollup := svcCtx.Config.Cron.Rollup
+			if !rollup.Enabled || src.verify == nil || len(bars) == 0 {
+				return written, nil
+			}
+			var verify upstreamBars
+			if rollup.Verify {
+				verify = src.verify(u.Market)
+			}
+			rollupCandles(ctx, repo, u.Market, bars[0].T, svcCtx.Resolutions.Market(ctx), verify)
+			return written, nil
+		},
+	}
+}
+
+// countbackSince 返回从 lastT 到现在需要补拉的根数，额外加上 extra 根。
+func countbackSince(lastT int64, resolution string, extra int) int {
+	return int((time.Now().Unix()-lastT)/candle.Seconds(resolution)) + extra
+}
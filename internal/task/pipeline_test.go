@@ -0,0 +1,87 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+func TestPipelineRetriesAndCounts(t *testing.T) {
+	attempts := map[string]int{}
+	retries := 1
+	p := &Pipeline[int, int]{
+		JobName: "test_fetch",
+		Units:   resolutionUnits([]string{"ok", "flaky", "down"}),
+		Fetch: func(_ context.Context, u unit) (int, error) {
+			attempts[u.Resolution]++
+			if u.Resolution == "down" || (u.Resolution == "flaky" && attempts[u.Resolution] == 1) {
+				return 0, errors.New("upstream error")
+			}
+			return 2, nil
+		},
+		Transform: func(_ unit, in int) (int, error) { return in * 2, nil },
+		Store:     func(_ context.Context, _ unit, out int) (int, error) { return out, nil },
+		Retries:   &retries,
+	}
+	res := p.Run(context.Background(), &svc.ServiceContext{})
+	if res.Units != 3 || res.Fetched != 2 || res.Written != 8 || res.Failed != 1 || len(res.Errors) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if attempts["ok"] != 1 || attempts["flaky"] != 2 || attempts["down"] != 2 {
+		t.Fatalf("unexpected attempts: %v", attempts)
+	}
+
+	// an explicit 0 disables retries even when Cron.FetchRetries is set
+	clear(attempts)
+	retries = 0
+	svcCtx := &svc.ServiceContext{}
+	svcCtx.Config.Cron.FetchRetries = 2
+	if res = p.Run(context.Background(), svcCtx); attempts["down"] != 1 || res.Failed != 2 {
+		t.Fatalf("unexpected run without retries: %+v, attempts %v", res, attempts)
+	}
+}
+
+func TestPipelineRunTargetOnlyRunsMatchingUnits(t *testing.T) {
+	var fetched []string
+	p := &Pipeline[int, int]{
+		JobName: "test_history_fetch",
+		Units: marketUnits(func() []string { return []string{"1", "5"} }, func(context.Context, string) ([]string, error) {
+			return []string{"m1", "m2"}, nil
+		}),
+		Fetch: func(_ context.Context, u unit) (int, error) {
+			fetched = append(fetched, u.String())
+			return 1, nil
+		},
+		Transform: identity[int],
+		Store:     func(_ context.Context, _ unit, out int) (int, error) { return out, nil },
+	}
+	res := p.RunTarget(context.Background(), &svc.ServiceContext{}, Target{Market: "m2"})
+	if res.Units != 2 || len(fetched) != 2 || fetched[0] != "m2@1" || fetched[1] != "m2@5" {
+		t.Fatalf("unexpected run: %+v, fetched %v", res, fetched)
+	}
+	if res = p.RunTarget(context.Background(), &svc.ServiceContext{}, Target{Market: "m3"}); res.Failed != 1 {
+		t.Fatalf("unmatched target should fail, got %+v", res)
+	}
+}
+
+func TestPipelineStopsDispatchingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fetched := 0
+	p := &Pipeline[int, int]{
+		JobName: "test_fetch",
+		Units:   resolutionUnits([]string{"1", "5", "15"}),
+		Fetch: func(context.Context, unit) (int, error) {
+			fetched++
+			cancel()
+			return 1, nil
+		},
+		Transform: identity[int],
+		Store:     func(_ context.Context, _ unit, out int) (int, error) { return out, nil },
+	}
+	res := p.Run(ctx, &svc.ServiceContext{})
+	if fetched != 1 || res.Fetched != 1 || res.Failed != 1 {
+		t.Fatalf("unexpected run after cancel: fetched %d, %+v", fetched, res)
+	}
+}
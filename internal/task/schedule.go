@@ -0,0 +1,101 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+	"github.com/biya-coin/injective-chronos-go/internal/schedule"
+	"github.com/biya-coin/injective-chronos-go/internal/svc"
+)
+
+// 任务名，同时也是任务锁的 key（pipeline 的任务名见 pipeline_kinds.go）
+const (
+	jobGapRepair     = "candle_gap_repair"
+	jobPrune         = "snapshot_prune"
+	jobMarketSync    = "market_registry_sync"
+	jobMarketOnboard = "market_onboard"
+)
+
+// defaultJobSchedule 返回任务的默认调度：config 与 symbol 元数据每小时拉取一次；K 线在每根 bar 收盘后
+// 稍等几秒拉取（周期取 Cron.IntervalSec，至少 1 分钟）；其余任务沿用 Cron.IntervalSec。
+func defaultJobSchedule(c config.Config, name string) config.JobSchedule {
+	every := c.Cron.IntervalSec
+	switch {
+	case name == jobGapRepair:
+		return config.JobSchedule{EverySec: c.Cron.GapRepair.IntervalSec, JitterSec: 30}
+	case name == jobPrune:
+		return config.JobSchedule{EverySec: c.Retention.IntervalSec, JitterSec: 60}
+	case strings.HasSuffix(name, "_config_fetch"),
+		strings.HasSuffix(name, "_symbol_info_fetch"),
+		strings.HasSuffix(name, "_symbols_fetch"):
+		return config.JobSchedule{EverySec: 3600, JitterSec: 60}
+	case strings.HasSuffix(name, "_history_fetch"):
+		return config.JobSchedule{Align: strconv.Itoa(max(every/60, 1)), OffsetSec: 3, JitterSec: 2}
+	}
+	return config.JobSchedule{EverySec: every, JitterSec: 5}
+}
+
+// jobSchedule merges the Cron.Jobs override of a job into its default.
+func jobSchedule(c config.Config, name string) config.JobSchedule {
+	s := defaultJobSchedule(c, name)
+	o, ok := c.Cron.Jobs[name]
+	if !ok {
+		return s
+	}
+	if o.EverySec > 0 || o.Cron != "" || o.Align != "" {
+		s.EverySec, s.Cron, s.Align, s.OffsetSec = o.EverySec, o.Cron, o.Align, o.OffsetSec
+	}
+	if o.JitterSec > 0 {
+		s.JitterSec = o.JitterSec
+	}
+	s.Disabled = o.Disabled
+	return s
+}
+
+// buildSchedule turns a JobSchedule into a schedule.Schedule.
+func buildSchedule(s config.JobSchedule) (schedule.Schedule, error) {
+	switch {
+	case s.Cron != "":
+		return schedule.ParseCron(s.Cron)
+	case s.Align != "":
+		return schedule.AlignTo(s.Align, time.Duration(s.OffsetSec)*time.Second)
+	case s.EverySec > 0:
+		return schedule.Every(time.Duration(s.EverySec) * time.Second), nil
+	}
+	return nil, fmt.Errorf("no EverySec, Cron or Align")
+}
+
+// addJob registers run under name with its configured schedule and records
+// every run in Store.JobRuns; disabled jobs are skipped. Runs of a job paused
+// through SetPaused are skipped without being recorded.
+func addJob(s *schedule.Scheduler, svcCtx *svc.ServiceContext, name string, run func(ctx context.Context) RunResult) error {
+	js := jobSchedule(svcCtx.Config, name)
+	if js.Disabled {
+		cronInfof("schedule %s: disabled", name)
+		return nil
+	}
+	sched, err := buildSchedule(js)
+	if err != nil {
+		return fmt.Errorf("schedule %s: %w", name, err)
+	}
+	cronInfof("schedule %s: %v, jitter %ds", name, sched, js.JitterSec)
+	s.Add(schedule.Job{
+		Name:     name,
+		Schedule: sched,
+		Jitter:   time.Duration(js.JitterSec) * time.Second,
+		Run: func(ctx context.Context) {
+			if paused, err := svcCtx.Store.JobStates.Paused(ctx, name); err != nil {
+				cronErrorf("schedule %s: read paused state: %v", name, err)
+			} else if paused {
+				cronInfof("%s: paused, skip this run", name)
+				return
+			}
+			recordRun(ctx, svcCtx, run(ctx))
+		},
+	})
+	return nil
+}
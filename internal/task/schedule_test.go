@@ -0,0 +1,32 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/biya-coin/injective-chronos-go/internal/config"
+)
+
+func TestJobScheduleMergesOverrides(t *testing.T) {
+	var c config.Config
+	c.Cron.IntervalSec = 60
+	c.Cron.Jobs = map[string]config.JobSchedule{
+		"spot_market_history_fetch": {EverySec: 30},
+		"spot_config_fetch":         {JitterSec: 5},
+		"derivative_symbols_fetch":  {Disabled: true},
+	}
+	if s := jobSchedule(c, "derivative_history_fetch"); s.Align != "1" || s.OffsetSec != 3 {
+		t.Fatalf("history default = %+v", s)
+	}
+	if s := jobSchedule(c, "spot_market_history_fetch"); s.EverySec != 30 || s.Align != "" || s.JitterSec != 2 {
+		t.Fatalf("timing override must replace alignment, got %+v", s)
+	}
+	if s := jobSchedule(c, "spot_config_fetch"); s.EverySec != 3600 || s.JitterSec != 5 {
+		t.Fatalf("jitter override must keep default timing, got %+v", s)
+	}
+	if s := jobSchedule(c, "derivative_symbols_fetch"); !s.Disabled {
+		t.Fatalf("expected disabled")
+	}
+	if _, err := buildSchedule(config.JobSchedule{Cron: "0 25 * * *"}); err == nil {
+		t.Fatalf("expected invalid cron to fail")
+	}
+}